@@ -0,0 +1,65 @@
+// Command auditverify checks the hash chain of audit logs written by
+// logger.Audit. It exits with status 1 if a record is missing, inserted or
+// modified.
+//
+//	auditverify [-key KEY] [-expect HASH] [-after-seq N -after-hash HASH] user-service-audit.log
+//
+// The key defaults to LOG_AUDIT_KEY. Records removed from the end of a log do
+// not break its chain, so compare the last hash printed with one kept
+// elsewhere through -expect. A log must start with the first record of the
+// chain, unless it continues from the record given by -after-seq and
+// -after-hash, e.g. after older records were archived.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/vaish1707/golang-logging-instrumentation/logger"
+)
+
+func main() {
+	key := flag.String("key", os.Getenv("LOG_AUDIT_KEY"), "HMAC key of the audit log")
+	expect := flag.String("expect", "", "expected hash of the last record")
+	afterSeq := flag.Uint64("after-seq", 0, "seq of the record the log continues from")
+	afterHash := flag.String("after-hash", "", "hash of the record the log continues from")
+	flag.Parse()
+	if flag.NArg() == 0 || (*afterSeq == 0) != (*afterHash == "") {
+		fmt.Fprintln(os.Stderr, "usage: auditverify [-key KEY] [-expect HASH] [-after-seq N -after-hash HASH] FILE...")
+		os.Exit(2)
+	}
+	if *key == "" {
+		fmt.Fprintln(os.Stderr, "warning: no key given, the records are only checked against plain SHA-256 hashes")
+	}
+
+	failed := false
+	anchor := logger.AuditAnchor{Seq: *afterSeq, Hash: *afterHash}
+	for _, path := range flag.Args() {
+		if err := verify(path, []byte(*key), anchor, *expect); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func verify(path string, key []byte, anchor logger.AuditAnchor, expect string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	summary, err := logger.VerifyAudit(f, key, anchor)
+	if err != nil {
+		return fmt.Errorf("%w (%d records verified)", err, summary.Records)
+	}
+	if expect != "" && summary.LastHash != expect {
+		return fmt.Errorf("last hash %s does not match %s, records were removed or replaced", summary.LastHash, expect)
+	}
+	fmt.Printf("%s: ok, %d records, last seq %d, last hash %s\n", path, summary.Records, summary.LastSeq, summary.LastHash)
+	return nil
+}
@@ -2,66 +2,112 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 
+	"github.com/vaish1707/golang-logging-instrumentation/logger"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	"google.golang.org/grpc/credentials"
 )
 
-// Init configures an OpenTelemetry exporter and trace provider
-func Init(serviceName string) (*sdktrace.TracerProvider, error) {
-	var (
-		signozToken  = os.Getenv("SIGNOZ_ACCESS_TOKEN")
-		collectorURL = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-		insecure     = os.Getenv("INSECURE_MODE")
-	)
-	headers := map[string]string{
-		"signoz-access-token": signozToken,
-	}
-
-	secureOption := otlptracegrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
-	if len(insecure) > 0 {
-		secureOption = otlptracegrpc.WithInsecure()
-	}
-
-	exporter, err := otlptrace.New(
-		context.Background(),
-		otlptracegrpc.NewClient(
-			secureOption,
-			otlptracegrpc.WithEndpoint(collectorURL),
-			otlptracegrpc.WithHeaders(headers),
-		),
-	)
-
+// Init configures an OpenTelemetry exporter and trace provider, and tees the
+// logger to the same collector as OTLP log records. It follows the standard
+// OTEL_* environment variables, see newTraceExporter for the exporters.
+//
+// The middlewares are chained in front of the processor which exports spans,
+// the first one outermost, so each can drop or change the spans the next one
+// and finally the exporter get.
+func Init(serviceName string, middlewares ...SpanProcessorMiddleware) (*sdktrace.TracerProvider, error) {
+	exporter, err := newTraceExporter(context.Background(), serviceName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	resources, err := resource.New(
-		context.Background(),
-		resource.WithAttributes(
-			attribute.String("service.name", serviceName),
-		),
-	)
+	exportLogs, err := logsExporterFromEnv()
 	if err != nil {
-		log.Printf("Could not set resources: %v", err)
+		return nil, err
+	}
+	resources := serviceResource(serviceName)
+	logger.SetResource(resources.Attributes())
+	if exportLogs {
+		addLogExporter(resources)
 	}
 
-	traceProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-		sdktrace.WithSpanProcessor(sdktrace.NewBatchSpanProcessor(exporter)),
-		sdktrace.WithSyncer(exporter),
+	traceProvider := newTracerProvider(exporter, spanProcessorConfigFromEnv(), middlewares,
+		sdktrace.WithSampler(samplerFromEnv()),
 		sdktrace.WithResource(resources),
 	)
 
 	otel.SetTracerProvider(traceProvider)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetTextMapPropagator(propagatorFromEnv())
 
 	return traceProvider, nil
 }
+
+// newTracerProvider registers the processor of cfg which passes spans to
+// exporter, behind middlewares, once.
+func newTracerProvider(exporter sdktrace.SpanExporter, cfg SpanProcessorConfig, middlewares []SpanProcessorMiddleware, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	p := chainSpanProcessors(NewSpanProcessor(exporter, cfg), middlewares)
+	opts = append(opts, sdktrace.WithSpanProcessor(p))
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// logsExporterFromEnv reports whether OTEL_LOGS_EXPORTER selects otlp rather
+// than none. Unset, log records go to the collector only if spans do, so
+// that OTEL_TRACES_EXPORTER=none, console or file runs without one.
+func logsExporterFromEnv() (bool, error) {
+	name, ok := envFirst("OTEL_LOGS_EXPORTER")
+	if !ok {
+		traces, _ := envFirst("OTEL_TRACES_EXPORTER")
+		return traces == "" || strings.EqualFold(traces, "otlp"), nil
+	}
+	switch strings.ToLower(name) {
+	case "otlp":
+		return true, nil
+	case "none":
+		return false, nil
+	}
+	return false, fmt.Errorf("unsupported OTEL_LOGS_EXPORTER %q", name)
+}
+
+// addLogExporter tees the logger to the collector as OTLP log records.
+func addLogExporter(resources *resource.Resource) {
+	logs := otlpSettingsFromEnv("LOGS")
+	protocol, _ := envFirst("OTEL_EXPORTER_OTLP_LOGS_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL")
+	logConfig := logger.OTLPConfig{
+		Protocol:      protocol,
+		Endpoint:      logs.Endpoint,
+		URLPath:       logs.URLPath,
+		Insecure:      logs.Insecure,
+		Headers:       logs.Headers,
+		Compression:   logs.Compression,
+		Resource:      resources.Attributes(),
+		Level:         logger.SinkLevel("otlp"),
+		ExportTimeout: logs.Timeout,
+	}
+	logBatchConfigFromEnv(&logConfig)
+	logCore, err := logger.NewOTLPCore(context.Background(), logConfig)
+	if err != nil {
+		log.Printf("Could not set log exporter: %v", err)
+	} else {
+		logger.AddCore(logCore)
+	}
+}
+
+// logBatchConfigFromEnv reads the OTEL_BLRP_* settings of the batch log
+// record processor.
+func logBatchConfigFromEnv(cfg *logger.OTLPConfig) {
+	if d, ok := envMillis("OTEL_BLRP_SCHEDULE_DELAY"); ok {
+		cfg.BatchTimeout = d
+	}
+	if d, ok := envMillis("OTEL_BLRP_EXPORT_TIMEOUT"); ok {
+		cfg.ExportTimeout = d
+	}
+	if n, ok := envPositiveInt("OTEL_BLRP_MAX_QUEUE_SIZE"); ok {
+		cfg.MaxQueueSize = n
+	}
+	if n, ok := envPositiveInt("OTEL_BLRP_MAX_EXPORT_BATCH_SIZE"); ok {
+		cfg.MaxExportBatchSize = n
+	}
+}
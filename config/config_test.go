@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestLogsExporterFromEnv(t *testing.T) {
+	for _, tt := range []struct {
+		traces, logs string
+		want         bool
+		wantErr      bool
+	}{
+		{want: true},
+		{traces: "otlp", want: true},
+		{traces: "none"},
+		{traces: "console"},
+		{traces: "file"},
+		{traces: "none", logs: "otlp", want: true},
+		{traces: "otlp", logs: "none"},
+		{logs: "OTLP", want: true},
+		{logs: "console", wantErr: true},
+	} {
+		t.Setenv("OTEL_TRACES_EXPORTER", tt.traces)
+		t.Setenv("OTEL_LOGS_EXPORTER", tt.logs)
+		got, err := logsExporterFromEnv()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("traces %q, logs %q: got %v, %v, want %v", tt.traces, tt.logs, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envFirst returns the value of the first of keys which is set and not
+// empty.
+func envFirst(keys ...string) (string, bool) {
+	for _, key := range keys {
+		if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// envMillis reads a duration in milliseconds, as the OTEL_* variables use.
+func envMillis(key string) (time.Duration, bool) {
+	v, ok := envFirst(key)
+	if !ok {
+		return 0, false
+	}
+	ms, err := strconv.Atoi(v)
+	if err != nil || ms < 0 {
+		log.Printf("Ignoring invalid %s %q", key, v)
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// envPositiveInt reads a positive integer.
+func envPositiveInt(key string) (int, bool) {
+	v, ok := envFirst(key)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("Ignoring invalid %s %q", key, v)
+		return 0, false
+	}
+	return n, true
+}
+
+// otlpSettings are the OTEL_EXPORTER_OTLP_* settings of one signal.
+type otlpSettings struct {
+	// Endpoint is the host:port of the collector.
+	Endpoint string
+	// URLPath is the path of the OTLP/HTTP endpoint, if it was given as a
+	// URL.
+	URLPath     string
+	Insecure    bool
+	Headers     map[string]string
+	Timeout     time.Duration
+	Compression string
+}
+
+// otlpSettingsFromEnv reads the OTEL_EXPORTER_OTLP_* variables of signal,
+// e.g. TRACES or LOGS, falling back to the ones shared by all signals.
+// INSECURE_MODE and SIGNOZ_ACCESS_TOKEN are still honoured.
+func otlpSettingsFromEnv(signal string) otlpSettings {
+	key := func(name string) []string {
+		return []string{"OTEL_EXPORTER_OTLP_" + signal + "_" + name, "OTEL_EXPORTER_OTLP_" + name}
+	}
+
+	s := otlpSettings{
+		Headers: map[string]string{},
+		Timeout: 10 * time.Second,
+	}
+	if endpoint, ok := envFirst(key("ENDPOINT")...); ok {
+		// The spec uses URLs, while SigNoz documents host:port.
+		if u, err := url.Parse(endpoint); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			s.Endpoint = u.Host
+			s.Insecure = u.Scheme == "http"
+			s.URLPath = u.Path
+			if _, signalSpecific := envFirst(key("ENDPOINT")[0]); !signalSpecific {
+				// The shared endpoint is the base URL of all signals.
+				s.URLPath = strings.TrimSuffix(u.Path, "/") + "/v1/" + strings.ToLower(signal)
+			}
+		} else {
+			s.Endpoint = endpoint
+		}
+	}
+	if v, ok := envFirst(key("INSECURE")...); ok {
+		s.Insecure, _ = strconv.ParseBool(v)
+	}
+	if os.Getenv("INSECURE_MODE") != "" {
+		s.Insecure = true
+	}
+
+	if v, ok := envFirst(key("HEADERS")...); ok {
+		s.Headers = parseHeaders(v)
+	}
+	if token := os.Getenv("SIGNOZ_ACCESS_TOKEN"); token != "" {
+		s.Headers["signoz-access-token"] = token
+	}
+
+	for _, k := range key("TIMEOUT") {
+		if timeout, ok := envMillis(k); ok {
+			s.Timeout = timeout
+			break
+		}
+	}
+	if v, ok := envFirst(key("COMPRESSION")...); ok {
+		switch v = strings.ToLower(v); v {
+		case "gzip":
+			s.Compression = v
+		case "none":
+		default:
+			log.Printf("Ignoring unsupported OTLP compression %q", v)
+		}
+	}
+	return s
+}
+
+// parseHeaders parses the key=value,key=value list of
+// OTEL_EXPORTER_OTLP_HEADERS, whose values are URL encoded.
+func parseHeaders(v string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(v, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = decoded
+		}
+		if key != "" {
+			headers[key] = value
+		}
+	}
+	return headers
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseHeaders(t *testing.T) {
+	got := parseHeaders("api-key=secret%3D1, x-tenant = a b ,invalid,=empty")
+	want := map[string]string{"api-key": "secret=1", "x-tenant": "a b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestOTLPSettingsFromEnv(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		env  map[string]string
+		want otlpSettings
+	}{
+		{
+			name: "defaults",
+			want: otlpSettings{Headers: map[string]string{}, Timeout: 10 * time.Second},
+		},
+		{
+			name: "shared URL",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":    "http://collector:4318/otlp/",
+				"OTEL_EXPORTER_OTLP_HEADERS":     "api-key=secret",
+				"OTEL_EXPORTER_OTLP_TIMEOUT":     "2500",
+				"OTEL_EXPORTER_OTLP_COMPRESSION": "GZIP",
+			},
+			want: otlpSettings{
+				Endpoint:    "collector:4318",
+				URLPath:     "/otlp/v1/traces",
+				Insecure:    true,
+				Headers:     map[string]string{"api-key": "secret"},
+				Timeout:     2500 * time.Millisecond,
+				Compression: "gzip",
+			},
+		},
+		{
+			name: "signal URL overrides the shared one",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://collector:4318",
+				"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "https://traces.example.com/api/traces",
+				"OTEL_EXPORTER_OTLP_TIMEOUT":         "-1",
+				"OTEL_EXPORTER_OTLP_COMPRESSION":     "zstd",
+			},
+			want: otlpSettings{
+				Endpoint: "traces.example.com",
+				URLPath:  "/api/traces",
+				Headers:  map[string]string{},
+				Timeout:  10 * time.Second,
+			},
+		},
+		{
+			name: "SigNoz host and port",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "ingest.signoz.io:443",
+				"INSECURE_MODE":               "true",
+				"SIGNOZ_ACCESS_TOKEN":         "token",
+			},
+			want: otlpSettings{
+				Endpoint: "ingest.signoz.io:443",
+				Insecure: true,
+				Headers:  map[string]string{"signoz-access-token": "token"},
+				Timeout:  10 * time.Second,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+				"OTEL_EXPORTER_OTLP_INSECURE", "OTEL_EXPORTER_OTLP_TRACES_INSECURE",
+				"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS",
+				"OTEL_EXPORTER_OTLP_TIMEOUT", "OTEL_EXPORTER_OTLP_TRACES_TIMEOUT",
+				"OTEL_EXPORTER_OTLP_COMPRESSION", "OTEL_EXPORTER_OTLP_TRACES_COMPRESSION",
+				"INSECURE_MODE", "SIGNOZ_ACCESS_TOKEN",
+			} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := otlpSettingsFromEnv("TRACES"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchSpanProcessorFromEnv(t *testing.T) {
+	t.Setenv("OTEL_BSP_SCHEDULE_DELAY", "250")
+	t.Setenv("OTEL_BSP_EXPORT_TIMEOUT", "soon")
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "100")
+	t.Setenv("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", "0")
+	want := SpanProcessorConfig{ScheduleDelay: 250 * time.Millisecond, MaxQueueSize: 100}
+	if got := spanProcessorConfigFromEnv(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
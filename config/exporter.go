@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// newTraceExporter builds the span exporter named by OTEL_TRACES_EXPORTER:
+//
+//   - otlp, the default, sends spans to a collector with the protocol of
+//     OTEL_EXPORTER_OTLP_PROTOCOL: grpc (default), http/protobuf or http/json.
+//   - console or stdout pretty prints spans to stdout.
+//   - file appends spans as JSON lines to TRACE_FILE, which defaults to
+//     LOG_DIR/<serviceName>-traces.jsonl.
+//   - none drops spans, so the services run without a collector.
+func newTraceExporter(ctx context.Context, serviceName string) (sdktrace.SpanExporter, error) {
+	name, _ := envFirst("OTEL_TRACES_EXPORTER")
+	switch strings.ToLower(name) {
+	case "", "otlp":
+		return newOTLPTraceExporter(ctx)
+	case "console", "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		return newFileTraceExporter(serviceName)
+	case "none":
+		return discardExporter{}, nil
+	}
+	return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", name)
+}
+
+func newOTLPTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	settings := otlpSettingsFromEnv("TRACES")
+	protocol, _ := envFirst("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL")
+
+	var client otlptrace.Client
+	switch strings.ToLower(protocol) {
+	case "", "grpc":
+		client = newGRPCTraceClient(settings)
+	case "http/protobuf":
+		client = newHTTPTraceClient(settings)
+	case "http/json":
+		client = newJSONTraceClient(settings)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", protocol)
+	}
+	return otlptrace.New(ctx, client)
+}
+
+func newGRPCTraceClient(s otlpSettings) otlptrace.Client {
+	secureOption := otlptracegrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
+	if s.Insecure {
+		secureOption = otlptracegrpc.WithInsecure()
+	}
+	opts := []otlptracegrpc.Option{
+		secureOption,
+		otlptracegrpc.WithHeaders(s.Headers),
+		otlptracegrpc.WithTimeout(s.Timeout),
+	}
+	if s.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(s.Endpoint))
+	}
+	if s.Compression != "" {
+		opts = append(opts, otlptracegrpc.WithCompressor(s.Compression))
+	}
+	return otlptracegrpc.NewClient(opts...)
+}
+
+func newHTTPTraceClient(s otlpSettings) otlptrace.Client {
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithHeaders(s.Headers),
+		otlptracehttp.WithTimeout(s.Timeout),
+	}
+	if s.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if s.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(s.Endpoint))
+	}
+	if s.URLPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(s.URLPath))
+	}
+	if s.Compression == "gzip" {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+	return otlptracehttp.NewClient(opts...)
+}
+
+// fileExporter writes spans as JSON lines to a file, which is closed on
+// Shutdown.
+type fileExporter struct {
+	*stdouttrace.Exporter
+	file *os.File
+}
+
+func newFileTraceExporter(serviceName string) (sdktrace.SpanExporter, error) {
+	path := os.Getenv("TRACE_FILE")
+	if path == "" {
+		name := "application"
+		if serviceName != "" {
+			name = serviceName
+		}
+		path = filepath.Join(os.Getenv("LOG_DIR"), name+"-traces.jsonl")
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileExporter{Exporter: exporter, file: file}, nil
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	if err := e.Exporter.Shutdown(ctx); err != nil {
+		return err
+	}
+	return e.file.Close()
+}
+
+// discardExporter drops every span.
+type discardExporter struct{}
+
+func (discardExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error { return nil }
+
+func (discardExporter) Shutdown(context.Context) error { return nil }
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric/global"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/grpc/credentials"
+)
+
+// metricsHandler serves the Prometheus endpoint once InitMetrics enabled it.
+var metricsHandler http.Handler = http.NotFoundHandler()
+
+// InitMetrics configures a meter provider with the same resource as the
+// tracer provider of Init and registers it globally. OTEL_METRICS_EXPORTER
+// lists its exporters:
+//
+//   - otlp, the default, pushes metrics to the collector every
+//     OTEL_METRIC_EXPORT_INTERVAL milliseconds.
+//   - prometheus serves them to be scraped through MetricsHandler.
+//   - none disables metrics.
+func InitMetrics(serviceName string) (*sdkmetric.MeterProvider, error) {
+	opts := []sdkmetric.Option{sdkmetric.WithResource(serviceResource(serviceName))}
+
+	names, ok := envFirst("OTEL_METRICS_EXPORTER")
+	if !ok {
+		names = "otlp"
+	}
+	for _, name := range strings.Split(names, ",") {
+		var reader sdkmetric.Reader
+		var err error
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "otlp":
+			reader, err = newOTLPMetricReader(context.Background())
+		case "prometheus":
+			reader, err = newPrometheusReader()
+		case "none", "":
+			continue
+		default:
+			err = fmt.Errorf("unsupported OTEL_METRICS_EXPORTER %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdkmetric.WithReader(reader))
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(opts...)
+	global.SetMeterProvider(meterProvider)
+	return meterProvider, nil
+}
+
+// MetricsHandler serves the metrics in the Prometheus format if
+// OTEL_METRICS_EXPORTER includes prometheus, and 404 otherwise.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metricsHandler.ServeHTTP(w, r)
+	})
+}
+
+// newOTLPMetricReader exports metrics over OTLP/gRPC with the
+// OTEL_EXPORTER_OTLP_METRICS_* settings.
+func newOTLPMetricReader(ctx context.Context) (sdkmetric.Reader, error) {
+	s := otlpSettingsFromEnv("METRICS")
+	secureOption := otlpmetricgrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
+	if s.Insecure {
+		secureOption = otlpmetricgrpc.WithInsecure()
+	}
+	opts := []otlpmetricgrpc.Option{
+		secureOption,
+		otlpmetricgrpc.WithHeaders(s.Headers),
+		otlpmetricgrpc.WithTimeout(s.Timeout),
+	}
+	if s.Endpoint != "" {
+		opts = append(opts, otlpmetricgrpc.WithEndpoint(s.Endpoint))
+	}
+	if s.Compression != "" {
+		opts = append(opts, otlpmetricgrpc.WithCompressor(s.Compression))
+	}
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var readerOpts []sdkmetric.PeriodicReaderOption
+	if d, ok := envMillis("OTEL_METRIC_EXPORT_INTERVAL"); ok && d > 0 {
+		readerOpts = append(readerOpts, sdkmetric.WithInterval(d))
+	}
+	if d, ok := envMillis("OTEL_METRIC_EXPORT_TIMEOUT"); ok && d > 0 {
+		readerOpts = append(readerOpts, sdkmetric.WithTimeout(d))
+	}
+	return sdkmetric.NewPeriodicReader(exporter, readerOpts...), nil
+}
+
+// newPrometheusReader collects metrics when MetricsHandler is scraped.
+func newPrometheusReader() (sdkmetric.Reader, error) {
+	registry := prometheus.NewRegistry()
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, err
+	}
+	metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return exporter, nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+)
+
+// useMetricsHandler restores the metrics handler and the global meter
+// provider when the test ends.
+func useMetricsHandler(t *testing.T) {
+	t.Helper()
+	savedHandler, savedProvider := metricsHandler, global.MeterProvider()
+	t.Cleanup(func() {
+		metricsHandler = savedHandler
+		global.SetMeterProvider(savedProvider)
+	})
+}
+
+func scrape(t *testing.T) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(body)
+}
+
+func TestMetricsHandlerServesPrometheus(t *testing.T) {
+	useMetricsHandler(t)
+	metricsHandler = http.NotFoundHandler()
+	if code, _ := scrape(t); code != http.StatusNotFound {
+		t.Errorf("status %d before prometheus was enabled, want 404", code)
+	}
+
+	t.Setenv("OTEL_METRICS_EXPORTER", "prometheus, none")
+	mp, err := InitMetrics("metrics-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp.Shutdown(context.Background())
+
+	counter, err := global.Meter("test").SyncInt64().Counter("http.server.requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Add(context.Background(), 3,
+		attribute.String("http.route", "/users/{id}"),
+		attribute.Int("http.status_code", 500),
+	)
+
+	code, body := scrape(t)
+	if code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	want := `http_server_requests_total{http_route="/users/{id}",http_status_code="500",otel_scope_name="test",otel_scope_version=""} 3`
+	if !strings.Contains(body, want) {
+		t.Errorf("body does not contain %s:\n%s", want, body)
+	}
+	if !strings.Contains(body, `service_name="metrics-test"`) {
+		t.Errorf("body has no service_name of the resource:\n%s", body)
+	}
+}
+
+func TestInitMetricsRejectsUnknownExporter(t *testing.T) {
+	t.Setenv("OTEL_METRICS_EXPORTER", "statsd")
+	if _, err := InitMetrics("metrics-test"); err == nil {
+		t.Error("accepted an unknown exporter")
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/vaish1707/golang-logging-instrumentation/internal/otlphttp"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// jsonTraceClient exports spans with OTLP/HTTP using the JSON encoding, which
+// otlptracehttp does not support.
+type jsonTraceClient struct {
+	client *otlphttp.Client
+}
+
+func newJSONTraceClient(s otlpSettings) *jsonTraceClient {
+	scheme := "https"
+	if s.Insecure {
+		scheme = "http"
+	}
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "localhost:4318"
+	}
+	path := s.URLPath
+	if path == "" {
+		path = "/v1/traces"
+	}
+	return &jsonTraceClient{client: &otlphttp.Client{
+		URL:     scheme + "://" + endpoint + path,
+		Headers: s.Headers,
+		Gzip:    s.Compression == "gzip",
+		HTTP:    &http.Client{Timeout: s.Timeout},
+	}}
+}
+
+func (c *jsonTraceClient) Start(context.Context) error { return nil }
+
+func (c *jsonTraceClient) Stop(context.Context) error {
+	c.client.Close()
+	return nil
+}
+
+func (c *jsonTraceClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	body, err := otlphttp.MarshalJSON(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+	if err := c.client.Post(ctx, "application/json", body); err != nil {
+		return fmt.Errorf("OTLP/HTTP export failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanProcessorConfig controls how ended spans reach the exporter.
+type SpanProcessorConfig struct {
+	// Simple exports every span synchronously when it ends instead of in
+	// batches. It is meant for debugging with the console exporter.
+	Simple bool
+	// MaxQueueSize is the number of spans buffered before new ones are
+	// dropped. Defaults to 2048.
+	MaxQueueSize int
+	// MaxExportBatchSize defaults to 512.
+	MaxExportBatchSize int
+	// ScheduleDelay is the longest a span waits before it is exported.
+	// Defaults to 5s.
+	ScheduleDelay time.Duration
+	// ExportTimeout bounds a single export call. Defaults to 30s.
+	ExportTimeout time.Duration
+}
+
+// NewSpanProcessor returns the processor which passes ended spans to
+// exporter.
+func NewSpanProcessor(exporter sdktrace.SpanExporter, cfg SpanProcessorConfig) sdktrace.SpanProcessor {
+	if cfg.Simple {
+		return sdktrace.NewSimpleSpanProcessor(exporter)
+	}
+	var opts []sdktrace.BatchSpanProcessorOption
+	if cfg.MaxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(cfg.MaxQueueSize))
+	}
+	if cfg.MaxExportBatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(cfg.MaxExportBatchSize))
+	}
+	if cfg.ScheduleDelay > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(cfg.ScheduleDelay))
+	}
+	if cfg.ExportTimeout > 0 {
+		opts = append(opts, sdktrace.WithExportTimeout(cfg.ExportTimeout))
+	}
+	return sdktrace.NewBatchSpanProcessor(exporter, opts...)
+}
+
+// SpanProcessorMiddleware wraps the processor which exports spans, the way an
+// HTTP middleware wraps a handler. The processor it returns decides what next
+// gets: it may drop a span by not passing it on, or change it by passing on a
+// wrapped sdktrace.ReadOnlySpan.
+type SpanProcessorMiddleware func(next sdktrace.SpanProcessor) sdktrace.SpanProcessor
+
+// chainSpanProcessors wraps p in middlewares. The first middleware sees spans
+// first.
+func chainSpanProcessors(p sdktrace.SpanProcessor, middlewares []SpanProcessorMiddleware) sdktrace.SpanProcessor {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		p = middlewares[i](p)
+	}
+	return p
+}
+
+// FilterSpans passes on the ended spans for which keep returns true and drops
+// the others, e.g. those of health checks.
+func FilterSpans(keep func(sdktrace.ReadOnlySpan) bool) SpanProcessorMiddleware {
+	return func(next sdktrace.SpanProcessor) sdktrace.SpanProcessor {
+		return &filterProcessor{next: next, keep: keep}
+	}
+}
+
+type filterProcessor struct {
+	next sdktrace.SpanProcessor
+	keep func(sdktrace.ReadOnlySpan) bool
+}
+
+func (p *filterProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(ctx, s)
+}
+
+func (p *filterProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if p.keep(s) {
+		p.next.OnEnd(s)
+	}
+}
+
+func (p *filterProcessor) Shutdown(ctx context.Context) error   { return p.next.Shutdown(ctx) }
+func (p *filterProcessor) ForceFlush(ctx context.Context) error { return p.next.ForceFlush(ctx) }
+
+// spanProcessorConfigFromEnv reads TRACE_PROCESSOR, batch or simple, and the
+// OTEL_BSP_* settings of the batch span processor. The specification has no
+// variable to choose the processor and reserves the OTEL_ prefix, so
+// TRACE_PROCESSOR is our own, named after TRACE_FILE.
+func spanProcessorConfigFromEnv() SpanProcessorConfig {
+	var cfg SpanProcessorConfig
+	if name, ok := envFirst("TRACE_PROCESSOR"); ok {
+		switch strings.ToLower(name) {
+		case "simple":
+			cfg.Simple = true
+		case "batch":
+		default:
+			log.Printf("Unsupported TRACE_PROCESSOR %q, batching spans", name)
+		}
+	}
+	cfg.ScheduleDelay, _ = envMillis("OTEL_BSP_SCHEDULE_DELAY")
+	cfg.ExportTimeout, _ = envMillis("OTEL_BSP_EXPORT_TIMEOUT")
+	cfg.MaxQueueSize, _ = envPositiveInt("OTEL_BSP_MAX_QUEUE_SIZE")
+	cfg.MaxExportBatchSize, _ = envPositiveInt("OTEL_BSP_MAX_EXPORT_BATCH_SIZE")
+	return cfg
+}
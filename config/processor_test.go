@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// countingProcessor counts the spans which end and passes them on.
+type countingProcessor struct {
+	next  sdktrace.SpanProcessor
+	mu    sync.Mutex
+	ended []trace.SpanID
+}
+
+func (p *countingProcessor) middleware(next sdktrace.SpanProcessor) sdktrace.SpanProcessor {
+	p.next = next
+	return p
+}
+
+func (p *countingProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(ctx, s)
+}
+
+func (p *countingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.mu.Lock()
+	p.ended = append(p.ended, s.SpanContext().SpanID())
+	p.mu.Unlock()
+	p.next.OnEnd(s)
+}
+
+func (p *countingProcessor) Shutdown(ctx context.Context) error   { return p.next.Shutdown(ctx) }
+func (p *countingProcessor) ForceFlush(ctx context.Context) error { return p.next.ForceFlush(ctx) }
+
+// renamedSpan is a span whose name was changed by a middleware.
+type renamedSpan struct {
+	sdktrace.ReadOnlySpan
+	name string
+}
+
+func (s renamedSpan) Name() string { return s.name }
+
+// renameSpans prefixes the names of the spans it passes on.
+type renameSpans struct {
+	sdktrace.SpanProcessor
+	prefix string
+}
+
+func (p renameSpans) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.SpanProcessor.OnEnd(renamedSpan{s, p.prefix + s.Name()})
+}
+
+func TestTracerProviderExportsEachSpanOnce(t *testing.T) {
+	for name, cfg := range map[string]SpanProcessorConfig{
+		"batch":  {MaxExportBatchSize: 4},
+		"simple": {Simple: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			custom := &countingProcessor{}
+			tp := newTracerProvider(exporter, cfg, []SpanProcessorMiddleware{custom.middleware})
+
+			const n = 10
+			started := map[trace.SpanID]bool{}
+			for i := 0; i < n; i++ {
+				_, span := tp.Tracer("test").Start(context.Background(), "span")
+				started[span.SpanContext().SpanID()] = true
+				span.End()
+			}
+			// Shutting down would also reset the in-memory exporter.
+			if err := tp.ForceFlush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer tp.Shutdown(context.Background())
+
+			exported := map[trace.SpanID]int{}
+			for _, s := range exporter.GetSpans() {
+				exported[s.SpanContext.SpanID()]++
+			}
+			if len(exporter.GetSpans()) != n {
+				t.Errorf("exported %d spans, want %d", len(exporter.GetSpans()), n)
+			}
+			for id := range started {
+				if exported[id] != 1 {
+					t.Errorf("span %s exported %d times, want once", id, exported[id])
+				}
+			}
+			if len(custom.ended) != n {
+				t.Errorf("custom processor saw %d spans, want %d", len(custom.ended), n)
+			}
+		})
+	}
+}
+
+func TestSpanProcessorConfigFromEnv(t *testing.T) {
+	t.Setenv("TRACE_PROCESSOR", "simple")
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "100")
+	t.Setenv("OTEL_BSP_SCHEDULE_DELAY", "250")
+	cfg := spanProcessorConfigFromEnv()
+	if !cfg.Simple || cfg.MaxQueueSize != 100 || cfg.ScheduleDelay.Milliseconds() != 250 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestSpanProcessorMiddlewaresChain(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	counted := &countingProcessor{}
+	tp := newTracerProvider(exporter, SpanProcessorConfig{Simple: true}, []SpanProcessorMiddleware{
+		// Sees every span, including the dropped health checks.
+		counted.middleware,
+		FilterSpans(func(s sdktrace.ReadOnlySpan) bool { return s.Name() != "GET /health" }),
+		func(next sdktrace.SpanProcessor) sdktrace.SpanProcessor {
+			return renameSpans{next, "users "}
+		},
+	})
+	defer tp.Shutdown(context.Background())
+
+	for _, name := range []string{"GET /health", "GET /users/{id}", "GET /health", "POST /users"} {
+		_, span := tp.Tracer("test").Start(context.Background(), name)
+		span.End()
+	}
+
+	var exported []string
+	for _, s := range exporter.GetSpans() {
+		exported = append(exported, s.Name)
+	}
+	if want := []string{"users GET /users/{id}", "users POST /users"}; !reflect.DeepEqual(exported, want) {
+		t.Errorf("exported %q, want %q", exported, want)
+	}
+	if len(counted.ended) != 4 {
+		t.Errorf("first middleware saw %d spans, want 4", len(counted.ended))
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+var (
+	resourcesMu sync.Mutex
+	resources   = map[string]*resource.Resource{}
+)
+
+// serviceResource returns the resource of serviceName, which is detected once
+// so that traces, metrics and logs share it.
+func serviceResource(serviceName string) *resource.Resource {
+	resourcesMu.Lock()
+	defer resourcesMu.Unlock()
+	if res, ok := resources[serviceName]; ok {
+		return res
+	}
+	res, err := newResource(context.Background(), serviceName)
+	if err != nil {
+		log.Printf("Could not detect all resource attributes: %v", err)
+	}
+	resources[serviceName] = res
+	return res
+}
+
+// newResource describes the running service: its name, version and
+// instance, the host, OS, process and Go runtime, the deployment environment,
+// the container and the Kubernetes pod. OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES take precedence over the detected attributes.
+//
+// A partial resource is returned with the error if a detector fails.
+func newResource(ctx context.Context, serviceName string) (*resource.Resource, error) {
+	return resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
+		resource.WithAttributes(serviceAttributes()...),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithOSType(),
+		resource.WithProcessPID(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithContainerID(),
+		resource.WithAttributes(kubernetesAttributes()...),
+		resource.WithFromEnv(),
+	)
+}
+
+// instanceID identifies this process among the instances of the service.
+var instanceID = uuid.New().String()
+
+// serviceAttributes returns service.instance.id, service.version and
+// deployment.environment. The version is SERVICE_VERSION, or else the module
+// version or VCS revision stamped into the binary. The environment is
+// DEPLOYMENT_ENVIRONMENT, or else APP_ENV.
+func serviceAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.ServiceInstanceIDKey.String(instanceID)}
+	if version := serviceVersion(); version != "" {
+		attrs = append(attrs, semconv.ServiceVersionKey.String(version))
+	}
+	if env, ok := envFirst("DEPLOYMENT_ENVIRONMENT", "APP_ENV"); ok {
+		attrs = append(attrs, semconv.DeploymentEnvironmentKey.String(env))
+	}
+	return attrs
+}
+
+func serviceVersion() string {
+	if version, ok := envFirst("SERVICE_VERSION"); ok {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// kubernetesAttributes reads the pod's identity from variables set through
+// the downward API, e.g.
+//
+//	env:
+//	- name: K8S_POD_NAME
+//	  valueFrom:
+//	    fieldRef:
+//	      fieldPath: metadata.name
+func kubernetesAttributes() []attribute.KeyValue {
+	vars := []struct {
+		env string
+		key attribute.Key
+	}{
+		{"K8S_NAMESPACE_NAME", semconv.K8SNamespaceNameKey},
+		{"K8S_POD_NAME", semconv.K8SPodNameKey},
+		{"K8S_POD_UID", semconv.K8SPodUIDKey},
+		{"K8S_NODE_NAME", semconv.K8SNodeNameKey},
+		{"K8S_CONTAINER_NAME", semconv.K8SContainerNameKey},
+		{"K8S_DEPLOYMENT_NAME", semconv.K8SDeploymentNameKey},
+	}
+	var attrs []attribute.KeyValue
+	for _, v := range vars {
+		if value := os.Getenv(v.env); value != "" {
+			attrs = append(attrs, v.key.String(value))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestNewResource(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "team=payments,deployment.environment=staging")
+	t.Setenv("SERVICE_VERSION", "1.4.2")
+	t.Setenv("DEPLOYMENT_ENVIRONMENT", "")
+	t.Setenv("APP_ENV", "prod")
+	t.Setenv("K8S_NAMESPACE_NAME", "shop")
+	t.Setenv("K8S_POD_NAME", "order-7d9f")
+	t.Setenv("K8S_POD_UID", "")
+
+	res, err := newResource(context.Background(), "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostname, _ := os.Hostname()
+	for key, want := range map[attribute.Key]string{
+		"service.name":        "order",
+		"service.version":     "1.4.2",
+		"service.instance.id": instanceID,
+		// OTEL_RESOURCE_ATTRIBUTES takes precedence over APP_ENV.
+		"deployment.environment": "staging",
+		"team":                   "payments",
+		"k8s.namespace.name":     "shop",
+		"k8s.pod.name":           "order-7d9f",
+		"host.name":              hostname,
+		"process.runtime.name":   "go",
+		"telemetry.sdk.language": "go",
+	} {
+		if got, ok := res.Set().Value(key); !ok || got.AsString() != want {
+			t.Errorf("%s = %q, want %q", key, got.AsString(), want)
+		}
+	}
+	if _, ok := res.Set().Value("k8s.pod.uid"); ok {
+		t.Error("added k8s.pod.uid without K8S_POD_UID")
+	}
+	if _, ok := res.Set().Value("process.pid"); !ok {
+		t.Error("no process.pid")
+	}
+}
+
+func TestNewResourceServiceNameFromEnv(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "order-canary")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "")
+	res, err := newResource(context.Background(), "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := res.Set().Value("service.name"); got.AsString() != "order-canary" {
+		t.Errorf("service.name = %q", got.AsString())
+	}
+}
+
+func TestServiceAttributesEnvironment(t *testing.T) {
+	t.Setenv("DEPLOYMENT_ENVIRONMENT", "")
+	t.Setenv("APP_ENV", "dev")
+	if got := attributeValue(serviceAttributes(), "deployment.environment"); got != "dev" {
+		t.Errorf("deployment.environment = %q from APP_ENV", got)
+	}
+	t.Setenv("DEPLOYMENT_ENVIRONMENT", "prod")
+	if got := attributeValue(serviceAttributes(), "deployment.environment"); got != "prod" {
+		t.Errorf("deployment.environment = %q from DEPLOYMENT_ENVIRONMENT", got)
+	}
+}
+
+func TestServiceResourceIsShared(t *testing.T) {
+	if serviceResource("resource-test") != serviceResource("resource-test") {
+		t.Error("detected the resource twice")
+	}
+}
+
+func attributeValue(attrs []attribute.KeyValue, key attribute.Key) string {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
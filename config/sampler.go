@@ -0,0 +1,47 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// samplerFromEnv builds the sampler named by OTEL_TRACES_SAMPLER with the
+// ratio in OTEL_TRACES_SAMPLER_ARG. If it is unset it is
+// parentbased_always_on, the default of the specification: root spans are
+// sampled and other spans follow the decision of their parent.
+func samplerFromEnv() sdktrace.Sampler {
+	name, ok := envFirst("OTEL_TRACES_SAMPLER")
+	if !ok {
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+
+	ratio := 1.0
+	if arg, ok := envFirst("OTEL_TRACES_SAMPLER_ARG"); ok {
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil || v < 0 || v > 1 {
+			log.Printf("Ignoring invalid OTEL_TRACES_SAMPLER_ARG %q, sampling every trace", arg)
+		} else {
+			ratio = v
+		}
+	}
+
+	switch strings.ToLower(name) {
+	case "always_on":
+		return sdktrace.AlwaysSample()
+	case "always_off":
+		return sdktrace.NeverSample()
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio)
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample())
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+	}
+	log.Printf("Unsupported OTEL_TRACES_SAMPLER %q, using parentbased_always_on", name)
+	return sdktrace.ParentBased(sdktrace.AlwaysSample())
+}
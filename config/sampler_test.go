@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSamplerFromEnv(t *testing.T) {
+	for _, tt := range []struct {
+		name, arg string
+		want      sdktrace.Sampler
+	}{
+		{"", "", sdktrace.ParentBased(sdktrace.AlwaysSample())},
+		{"always_on", "", sdktrace.AlwaysSample()},
+		{"always_off", "", sdktrace.NeverSample()},
+		{"TraceIdRatio", "0.25", sdktrace.TraceIDRatioBased(0.25)},
+		{"traceidratio", "", sdktrace.TraceIDRatioBased(1)},
+		{"traceidratio", "1.5", sdktrace.TraceIDRatioBased(1)},
+		{"parentbased_always_on", "", sdktrace.ParentBased(sdktrace.AlwaysSample())},
+		{"parentbased_always_off", "", sdktrace.ParentBased(sdktrace.NeverSample())},
+		{"parentbased_traceidratio", "0.1", sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.1))},
+		{"jaeger_remote", "", sdktrace.ParentBased(sdktrace.AlwaysSample())},
+	} {
+		t.Setenv("OTEL_TRACES_SAMPLER", tt.name)
+		t.Setenv("OTEL_TRACES_SAMPLER_ARG", tt.arg)
+		if got, want := samplerFromEnv().Description(), tt.want.Description(); got != want {
+			t.Errorf("%q, %q: got %s, want %s", tt.name, tt.arg, got, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package otlphttp sends OTLP export requests over HTTP for the log and trace
+// exporters, including the OTLP/JSON encoding which the otel exporters do not
+// support.
+package otlphttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Client posts export requests to an OTLP/HTTP endpoint.
+type Client struct {
+	URL     string
+	Headers map[string]string
+	Gzip    bool
+	HTTP    *http.Client
+}
+
+// Post sends body, encoded as contentType, and returns an error unless the
+// endpoint answers with a 2xx status.
+func (c *Client) Post(ctx context.Context, contentType string, body []byte) error {
+	if c.Gzip {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Close closes the idle connections of the client.
+func (c *Client) Close() {
+	c.HTTP.CloseIdleConnections()
+}
+
+// MarshalJSON encodes req as OTLP/JSON. Unlike the protobuf JSON mapping,
+// OTLP/JSON wants enums as numbers and trace and span IDs as hex instead of
+// base64.
+func MarshalJSON(req proto.Message) ([]byte, error) {
+	b, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	hexIDs(doc)
+	return json.Marshal(doc)
+}
+
+// hexIDs re-encodes the base64 traceId, spanId and parentSpanId values in doc
+// as hex.
+func hexIDs(doc interface{}) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			switch k {
+			case "traceId", "spanId", "parentSpanId":
+				if s, ok := value.(string); ok {
+					if id, err := base64.StdEncoding.DecodeString(s); err == nil {
+						v[k] = hex.EncodeToString(id)
+					}
+				}
+			default:
+				hexIDs(value)
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			hexIDs(value)
+		}
+	}
+}
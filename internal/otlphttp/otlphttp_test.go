@@ -0,0 +1,76 @@
+package otlphttp
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestMarshalJSONHexIDs(t *testing.T) {
+	req := &coltracepb.ExportTraceServiceRequest{ResourceSpans: []*tracepb.ResourceSpans{{
+		ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{
+			TraceId:      []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanId:       []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+			ParentSpanId: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+			Kind:         tracepb.Span_SPAN_KIND_SERVER,
+		}}}},
+	}}}
+	b, err := MarshalJSON(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		`"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+		`"spanId":"00f067aa0ba902b7"`,
+		`"parentSpanId":"0102030405060708"`,
+		`"kind":2`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%s does not contain %s", got, want)
+		}
+	}
+}
+
+func TestClientPostGzip(t *testing.T) {
+	var body, encoding, contentType, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding, contentType, auth = r.Header.Get("Content-Encoding"), r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b, _ := io.ReadAll(zr)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	c := &Client{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}, Gzip: true, HTTP: srv.Client()}
+	if err := c.Post(context.Background(), "application/json", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if body != `{}` || encoding != "gzip" || contentType != "application/json" || auth != "Bearer t" {
+		t.Errorf("got body %q, Content-Encoding %q, Content-Type %q, Authorization %q", body, encoding, contentType, auth)
+	}
+}
+
+func TestClientPostFailsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := &Client{URL: srv.URL, HTTP: srv.Client()}
+	err := c.Post(context.Background(), "application/x-protobuf", nil)
+	if err == nil || err.Error() != "429 Too Many Requests: quota exceeded" {
+		t.Errorf("error = %v", err)
+	}
+}
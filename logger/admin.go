@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// AdminAuth guards the admin endpoints with the bearer token in
+// LOG_ADMIN_TOKEN. Without a token they are disabled, since they would
+// otherwise let anyone who can reach the service change its logging.
+func AdminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("LOG_ADMIN_TOKEN")
+		if token == "" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"message": "admin endpoints are disabled, set LOG_ADMIN_TOKEN to enable them"})
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
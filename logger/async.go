@@ -0,0 +1,256 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// OverflowPolicy decides what an AsyncWriter does when its queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for room in the queue.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropNewest drops the entry being written.
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowDropOldest drops the oldest queued entry to make room.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+)
+
+var errAsyncWriterClosed = errors.New("async writer is closed")
+
+// AsyncStats are the counters of an AsyncWriter.
+type AsyncStats struct {
+	Queued  uint64 `json:"queued"`
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	// Pending is the number of entries waiting in the queue.
+	Pending int `json:"pending"`
+}
+
+// AsyncWriter moves writes off the caller's goroutine: entries go into a
+// bounded queue which a background goroutine writes to the wrapped
+// writer.
+type AsyncWriter struct {
+	out    entryWriter
+	policy OverflowPolicy
+	queue  chan queuedEntry
+	flush  chan chan error
+
+	// mu guards closed against concurrent writes, which must not send on
+	// the queue once it is closed.
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+
+	queued, written, dropped uint64
+}
+
+// queuedEntry is an entry waiting in the queue of an AsyncWriter. The entry
+// is only set for the network sinks, which need its level and time.
+type queuedEntry struct {
+	ent zapcore.Entry
+	p   []byte
+}
+
+// syncerWriter adapts a WriteSyncer to an entryWriter.
+type syncerWriter struct {
+	zapcore.WriteSyncer
+}
+
+func (w syncerWriter) WriteEntry(_ zapcore.Entry, p []byte) error {
+	_, err := w.Write(p)
+	return err
+}
+
+func (w syncerWriter) Close() error {
+	if c, ok := w.WriteSyncer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewAsyncWriter starts the background writer. A size of zero or less
+// defaults to 4096 entries, an empty policy to OverflowBlock.
+func NewAsyncWriter(out zapcore.WriteSyncer, size int, policy OverflowPolicy) *AsyncWriter {
+	return newAsyncEntryWriter(syncerWriter{out}, size, policy)
+}
+
+func newAsyncEntryWriter(out entryWriter, size int, policy OverflowPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = 4096
+	}
+	if policy == "" {
+		policy = OverflowBlock
+	}
+	w := &AsyncWriter{
+		out:    out,
+		policy: policy,
+		queue:  make(chan queuedEntry, size),
+		flush:  make(chan chan error),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	if err := w.WriteEntry(zapcore.Entry{}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteEntry queues p with the entry it encodes.
+func (w *AsyncWriter) WriteEntry(ent zapcore.Entry, p []byte) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return errAsyncWriterClosed
+	}
+
+	// zap reuses p once Write returns.
+	entry := queuedEntry{ent: ent, p: append([]byte(nil), p...)}
+	switch w.policy {
+	case OverflowDropNewest:
+		select {
+		case w.queue <- entry:
+		default:
+			atomic.AddUint64(&w.dropped, 1)
+			return nil
+		}
+	case OverflowDropOldest:
+		for sent := false; !sent; {
+			select {
+			case w.queue <- entry:
+				sent = true
+			default:
+				select {
+				case <-w.queue:
+					atomic.AddUint64(&w.dropped, 1)
+				default:
+				}
+			}
+		}
+	default:
+		w.queue <- entry
+	}
+	atomic.AddUint64(&w.queued, 1)
+	return nil
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for {
+		select {
+		case entry, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			w.write(entry)
+		case flushed := <-w.flush:
+			w.drain()
+			flushed <- w.out.Sync()
+		}
+	}
+}
+
+func (w *AsyncWriter) write(entry queuedEntry) {
+	if err := w.out.WriteEntry(entry.ent, entry.p); err != nil {
+		fmt.Fprintf(os.Stderr, "async log write error: %v\n", err)
+		return
+	}
+	atomic.AddUint64(&w.written, 1)
+}
+
+// drain writes the entries queued so far.
+func (w *AsyncWriter) drain() {
+	for {
+		select {
+		case entry, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			w.write(entry)
+		default:
+			return
+		}
+	}
+}
+
+// Sync waits until the queued entries are written and syncs the wrapped
+// writer.
+func (w *AsyncWriter) Sync() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return nil
+	}
+	flushed := make(chan error, 1)
+	w.flush <- flushed
+	return <-flushed
+}
+
+// Close writes the queued entries, then syncs and closes the wrapped
+// writer. Later writes fail.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	err := w.out.Sync()
+	if closeErr := w.out.Close(); closeErr != nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Stats returns the counters of the writer.
+func (w *AsyncWriter) Stats() AsyncStats {
+	return AsyncStats{
+		Queued:  atomic.LoadUint64(&w.queued),
+		Written: atomic.LoadUint64(&w.written),
+		Dropped: atomic.LoadUint64(&w.dropped),
+		Pending: len(w.queue),
+	}
+}
+
+// asyncConfigFromEnv reads the queue of the file sink. The queue is only used
+// if LOG_ASYNC is set.
+func asyncConfigFromEnv() (size int, policy OverflowPolicy, ok bool) {
+	if !envBool("LOG_ASYNC", false) {
+		return 0, "", false
+	}
+	policy = OverflowPolicy(os.Getenv("LOG_ASYNC_OVERFLOW"))
+	switch policy {
+	case "", OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid LOG_ASYNC_OVERFLOW %q, blocking instead\n", policy)
+		policy = OverflowBlock
+	}
+	return envInt("LOG_ASYNC_QUEUE_SIZE", 4096), policy, true
+}
+
+// AsyncHandler serves the counters of the file sink's queue.
+func AsyncHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fileQueue == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "async logging is disabled"})
+			return
+		}
+		writeJSON(w, http.StatusOK, fileQueue.Stats())
+	})
+}
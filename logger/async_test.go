@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slowSyncer is a WriteSyncer standing in for a slow disk.
+type slowSyncer struct {
+	delay time.Duration
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *slowSyncer) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *slowSyncer) Sync() error { return nil }
+
+func (s *slowSyncer) lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Split(strings.TrimSpace(s.buf.String()), "\n")
+}
+
+func TestAsyncWriterFlushesOnClose(t *testing.T) {
+	out := &slowSyncer{}
+	w := NewAsyncWriter(out, 0, OverflowBlock)
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(out.lines(), ","); got != "a,b,c" {
+		t.Errorf("written %q, want a,b,c", got)
+	}
+	if _, err := w.Write([]byte("d\n")); err != errAsyncWriterClosed {
+		t.Errorf("write after close = %v, want %v", err, errAsyncWriterClosed)
+	}
+	if stats := w.Stats(); stats.Queued != 3 || stats.Written != 3 || stats.Dropped != 0 {
+		t.Errorf("stats = %+v, want 3 queued and written", stats)
+	}
+}
+
+func TestAsyncWriterOverflowPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		policy OverflowPolicy
+		// want is the first entry written after the writer was released.
+		want string
+	}{
+		{OverflowDropNewest, "0"},
+		{OverflowDropOldest, "9"},
+	} {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			stalled := blockingWriter{release: make(chan struct{})}
+			var written []string
+			var mu sync.Mutex
+			w := newAsyncEntryWriter(recordingWriter{stalled, &mu, &written}, 1, tt.policy)
+
+			// The first entry is taken by the background goroutine, which
+			// then waits. The queue holds one more.
+			w.Write([]byte("first"))
+			for len(w.queue) != 0 {
+				time.Sleep(time.Millisecond)
+			}
+			for i := 0; i < 10; i++ {
+				w.Write([]byte{'0' + byte(i)})
+			}
+			close(stalled.release)
+			w.Close()
+
+			if stats := w.Stats(); stats.Dropped != 9 {
+				t.Errorf("dropped %d, want 9", stats.Dropped)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(written) != 2 || written[1] != tt.want {
+				t.Errorf("written %q, want [first %s]", written, tt.want)
+			}
+		})
+	}
+}
+
+// recordingWriter records the entries passed on to an entryWriter.
+type recordingWriter struct {
+	entryWriter
+	mu      *sync.Mutex
+	written *[]string
+}
+
+func (w recordingWriter) WriteEntry(ent zapcore.Entry, p []byte) error {
+	err := w.entryWriter.WriteEntry(ent, p)
+	w.mu.Lock()
+	*w.written = append(*w.written, string(p))
+	w.mu.Unlock()
+	return err
+}
+
+func newBenchmarkLogger(ws zapcore.WriteSyncer) *zap.Logger {
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return zap.New(zapcore.NewCore(enc, ws, zapcore.InfoLevel))
+}
+
+func benchmarkLogger(b *testing.B, l *zap.Logger) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("order created", zap.String("requestId", "req-1"), zap.Int("amount", 42))
+	}
+	b.StopTimer()
+}
+
+func openBenchmarkFile(b *testing.B) *os.File {
+	f, err := os.OpenFile(filepath.Join(b.TempDir(), "bench.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return f
+}
+
+// The benchmarks compare the time a log call takes on the caller's goroutine
+// with the synchronous file sink and with the AsyncWriter in front of it.
+
+func BenchmarkFileSync(b *testing.B) {
+	f := openBenchmarkFile(b)
+	defer f.Close()
+	benchmarkLogger(b, newBenchmarkLogger(zapcore.AddSync(f)))
+}
+
+func BenchmarkFileAsync(b *testing.B) {
+	for _, policy := range []OverflowPolicy{OverflowBlock, OverflowDropNewest, OverflowDropOldest} {
+		b.Run(string(policy), func(b *testing.B) {
+			w := NewAsyncWriter(zapcore.AddSync(openBenchmarkFile(b)), 4096, policy)
+			defer w.Close()
+			benchmarkLogger(b, newBenchmarkLogger(w))
+		})
+	}
+}
+
+// The slow benchmarks stand in for a disk taking 50µs per write.
+
+func BenchmarkSlowDiskSync(b *testing.B) {
+	benchmarkLogger(b, newBenchmarkLogger(&slowSyncer{delay: 50 * time.Microsecond}))
+}
+
+func BenchmarkSlowDiskAsync(b *testing.B) {
+	for _, policy := range []OverflowPolicy{OverflowBlock, OverflowDropNewest} {
+		b.Run(string(policy), func(b *testing.B) {
+			w := NewAsyncWriter(&slowSyncer{delay: 50 * time.Microsecond}, 4096, policy)
+			defer w.Close()
+			benchmarkLogger(b, newBenchmarkLogger(w))
+			b.ReportMetric(float64(w.Stats().Dropped)/float64(b.N), "dropped/op")
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AuditEvent is a change of a user's balance.
+type AuditEvent struct {
+	// Action names the change, e.g. top-up or debit.
+	Action string
+	// Actor is the authenticated principal which made the change, i.e. the
+	// service.
+	Actor string
+	// RequestedBy is the user the caller says requested the change, e.g.
+	// from baggage. It is not authenticated and is recorded as
+	// requested_by_unverified.
+	RequestedBy string
+	UserID      string
+	Before      int
+	After       int
+	RequestID   string
+}
+
+// AuditRecord is a line of the audit log. Hash covers the record, including
+// the hash of the previous one, so that an edited, inserted or removed record
+// breaks the chain.
+type AuditRecord struct {
+	Seq         uint64    `json:"seq"`
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	Actor       string    `json:"actor"`
+	RequestedBy string    `json:"requested_by_unverified,omitempty"`
+	UserID      string    `json:"userId"`
+	Before      int       `json:"before"`
+	After       int       `json:"after"`
+	TraceID     string    `json:"trace_id,omitempty"`
+	RequestID   string    `json:"requestId,omitempty"`
+	PrevHash    string    `json:"prev_hash"`
+	Hash        string    `json:"hash"`
+}
+
+// sum returns the hash of r, which is an HMAC if key is set.
+func (r AuditRecord) sum(key []byte) (string, error) {
+	r.Hash = ""
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AuditLogger appends hash-chained records to a file. It is kept apart from
+// the other sinks: records are never sampled, redacted or rotated, and each
+// one is synced to disk before Record returns.
+type AuditLogger struct {
+	mu       sync.Mutex
+	file     *os.File
+	key      []byte
+	seq      uint64
+	lastHash string
+}
+
+// OpenAuditLogger opens the audit log at path and continues its chain. The
+// key, if any, must be the same for the whole file.
+func OpenAuditLogger(path string, key []byte) (*AuditLogger, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	a := &AuditLogger{file: file, key: key}
+	last, size, err := lastAuditRecord(file)
+	if err == nil {
+		err = truncateTornRecord(file, size)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+	if last != nil {
+		a.seq, a.lastHash = last.Seq, last.Hash
+	}
+	return a, nil
+}
+
+// lastAuditRecord returns the last record of r, or nil if it has none, and
+// the size of its complete lines. A final line without a line ending is the
+// record of a write cut short by a crash and is not returned.
+func lastAuditRecord(r io.Reader) (*AuditRecord, int64, error) {
+	var last *AuditRecord
+	var size int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return last, size, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, 0, fmt.Errorf("record after seq %d: %w", seqOf(last), err)
+		}
+		last = &record
+		size += int64(len(line))
+	}
+}
+
+// truncateTornRecord cuts the file back to size, the end of its last complete
+// record, so that the next record starts on a line of its own.
+func truncateTornRecord(file *os.File, size int64) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == size {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "audit log %s: removing %d bytes of an incomplete record\n", file.Name(), info.Size()-size)
+	return file.Truncate(size)
+}
+
+func seqOf(r *AuditRecord) uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.Seq
+}
+
+// Record appends ev to the chain with the trace ID of ctx.
+func (a *AuditLogger) Record(ctx context.Context, ev AuditEvent) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return errors.New("audit log is closed")
+	}
+
+	record := AuditRecord{
+		Seq:         a.seq + 1,
+		Time:        time.Now().UTC(),
+		Action:      ev.Action,
+		Actor:       ev.Actor,
+		RequestedBy: ev.RequestedBy,
+		UserID:      ev.UserID,
+		Before:      ev.Before,
+		After:       ev.After,
+		RequestID:   ev.RequestID,
+		PrevHash:    a.lastHash,
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.TraceID = sc.TraceID().String()
+	}
+	sum, err := record.sum(a.key)
+	if err != nil {
+		return err
+	}
+	record.Hash = sum
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := a.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := a.file.Sync(); err != nil {
+		return err
+	}
+	a.seq, a.lastHash = record.Seq, record.Hash
+	return nil
+}
+
+// Close closes the audit log.
+func (a *AuditLogger) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// AuditSummary describes a verified chain. LastHash should be kept outside
+// the log, since removing records from the end does not break the chain.
+type AuditSummary struct {
+	Records  uint64
+	LastSeq  uint64
+	LastHash string
+}
+
+// AuditAnchor is the last record before a log, for logs which continue the
+// chain of records archived elsewhere. The zero value starts a chain.
+type AuditAnchor struct {
+	Seq  uint64
+	Hash string
+}
+
+// VerifyAudit checks the chain read from r: that it continues from anchor,
+// that sequence numbers have no gaps, that each record links to the previous
+// one and that no record was edited. It returns the summary of the records
+// verified before the first broken one.
+func VerifyAudit(r io.Reader, key []byte, anchor AuditAnchor) (AuditSummary, error) {
+	summary := AuditSummary{LastSeq: anchor.Seq, LastHash: anchor.Hash}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return summary, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Seq != summary.LastSeq+1 {
+			return summary, fmt.Errorf("line %d: expected seq %d, got %d", line, summary.LastSeq+1, record.Seq)
+		}
+		if record.PrevHash != summary.LastHash {
+			return summary, fmt.Errorf("line %d: seq %d does not link to the previous record", line, record.Seq)
+		}
+		sum, err := record.sum(key)
+		if err != nil {
+			return summary, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !hmac.Equal([]byte(sum), []byte(record.Hash)) {
+			return summary, fmt.Errorf("line %d: seq %d was modified", line, record.Seq)
+		}
+		summary.Records++
+		summary.LastSeq, summary.LastHash = record.Seq, record.Hash
+	}
+	return summary, scanner.Err()
+}
+
+var (
+	auditMu   sync.Mutex
+	audit     *AuditLogger
+	auditPath string
+	auditKey  []byte
+)
+
+// auditConfigFromEnv sets up the package audit log, which is opened on the
+// first Audit call. It defaults to LOG_DIR/<serviceName>-audit.log.
+func auditConfigFromEnv(serviceName string) {
+	path := os.Getenv("LOG_AUDIT_FILE")
+	if path == "" {
+		name := "application"
+		if serviceName != "" {
+			name = serviceName
+		}
+		path = filepath.Join(os.Getenv("LOG_DIR"), name+"-audit.log")
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if audit != nil {
+		// Reopened with the new settings by the next Audit call.
+		audit.Close()
+		audit = nil
+	}
+	auditPath = path
+	auditKey = []byte(os.Getenv("LOG_AUDIT_KEY"))
+}
+
+// Audit appends ev to the package audit log, see AuditLogger.
+func Audit(ctx context.Context, ev AuditEvent) error {
+	auditMu.Lock()
+	if audit == nil {
+		if len(auditKey) == 0 {
+			// Anyone who can write the file can rewrite a plain SHA-256
+			// chain and recompute every hash.
+			fmt.Fprintf(os.Stderr, "WARNING: LOG_AUDIT_KEY is not set, the records of %s are not authenticated and can be forged\n", auditPath)
+		}
+		a, err := OpenAuditLogger(auditPath, auditKey)
+		if err != nil {
+			auditMu.Unlock()
+			return err
+		}
+		audit = a
+	}
+	a := audit
+	auditMu.Unlock()
+	return a.Record(ctx, ev)
+}
+
+// RevertAudit appends the record of ev not being applied after all, with the
+// action suffixed by -reverted and the balances swapped. It is used when the
+// change fails after Audit recorded it.
+func RevertAudit(ctx context.Context, ev AuditEvent) error {
+	ev.Action += "-reverted"
+	ev.Before, ev.After = ev.After, ev.Before
+	return Audit(ctx, ev)
+}
+
+// closeAudit closes the package audit log.
+func closeAudit() error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if audit == nil {
+		return nil
+	}
+	err := audit.Close()
+	audit = nil
+	return err
+}
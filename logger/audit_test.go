@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testAuditKey = []byte("test-key")
+
+// writeAuditLog records n top-ups in a new audit log and returns its path.
+func writeAuditLog(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	a, err := OpenAuditLogger(path, testAuditKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := a.Record(context.Background(), AuditEvent{Action: "top-up", Actor: "u1", UserID: "u1", Before: i, After: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readAuditLines(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitAfter(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func TestVerifyAudit(t *testing.T) {
+	lines := readAuditLines(t, writeAuditLog(t, 4))
+	summary, err := VerifyAudit(strings.NewReader(strings.Join(lines, "")), testAuditKey, AuditAnchor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Records != 4 || summary.LastSeq != 4 {
+		t.Errorf("summary = %+v, want 4 records", summary)
+	}
+
+	for name, log := range map[string]string{
+		"leading record removed": strings.Join(lines[1:], ""),
+		"record removed":         lines[0] + lines[2] + lines[3],
+		"records swapped":        lines[0] + lines[2] + lines[1] + lines[3],
+		"record modified":        lines[0] + strings.Replace(lines[1], `"after":2`, `"after":200`, 1) + lines[2],
+	} {
+		if _, err := VerifyAudit(strings.NewReader(log), testAuditKey, AuditAnchor{}); err == nil {
+			t.Errorf("%s: chain verified", name)
+		}
+	}
+	if _, err := VerifyAudit(strings.NewReader(strings.Join(lines, "")), []byte("other-key"), AuditAnchor{}); err == nil {
+		t.Error("chain verified with another key")
+	}
+}
+
+func TestVerifyAuditFromAnchor(t *testing.T) {
+	lines := readAuditLines(t, writeAuditLog(t, 4))
+	archived, err := VerifyAudit(strings.NewReader(strings.Join(lines[:2], "")), testAuditKey, AuditAnchor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anchor := AuditAnchor{Seq: archived.LastSeq, Hash: archived.LastHash}
+	summary, err := VerifyAudit(strings.NewReader(strings.Join(lines[2:], "")), testAuditKey, anchor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Records != 2 || summary.LastSeq != 4 {
+		t.Errorf("summary = %+v, want 2 records up to seq 4", summary)
+	}
+	if _, err := VerifyAudit(strings.NewReader(strings.Join(lines[3:], "")), testAuditKey, anchor); err == nil {
+		t.Error("chain verified with a record missing after the anchor")
+	}
+}
+
+func TestAuditLoggerRemovesTornRecord(t *testing.T) {
+	path := writeAuditLog(t, 2)
+	// A crash in the middle of writing the third record.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":3,"time":"2026-`)
+	f.Close()
+
+	a, err := OpenAuditLogger(path, testAuditKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record(context.Background(), AuditEvent{Action: "debit", Actor: "u1", UserID: "u1", Before: 2, After: 1}); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := VerifyAudit(bytes.NewReader(b), testAuditKey, AuditAnchor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Records != 3 || summary.LastSeq != 3 {
+		t.Errorf("summary = %+v, want 3 records", summary)
+	}
+}
+
+func TestAuditLoggerRejectsCorruptRecord(t *testing.T) {
+	path := writeAuditLog(t, 2)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not a record\n")
+	f.Close()
+
+	if _, err := OpenAuditLogger(path, testAuditKey); err == nil {
+		t.Error("opened an audit log with a complete but corrupt record")
+	}
+}
+
+// useAuditLog points the package audit log at path until the test ends.
+func useAuditLog(t *testing.T, path string) {
+	t.Helper()
+	closeAudit()
+	auditMu.Lock()
+	savedPath, savedKey := auditPath, auditKey
+	auditPath, auditKey = path, testAuditKey
+	auditMu.Unlock()
+	t.Cleanup(func() {
+		closeAudit()
+		auditMu.Lock()
+		auditPath, auditKey = savedPath, savedKey
+		auditMu.Unlock()
+	})
+}
+
+func TestRevertAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	useAuditLog(t, path)
+	ev := AuditEvent{Action: "debit", Actor: "order-service", RequestedBy: "u2", UserID: "u1", Before: 10, After: 4}
+	if err := Audit(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+	if err := RevertAudit(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+	closeAudit()
+
+	lines := readAuditLines(t, path)
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want 2", len(lines))
+	}
+	for _, want := range []string{`"action":"debit-reverted"`, `"actor":"order-service"`, `"requested_by_unverified":"u2"`, `"before":4`, `"after":10`} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("record %s does not contain %s", lines[1], want)
+		}
+	}
+}
+
+func TestRevertAuditFailsWhenLogCannotBeOpened(t *testing.T) {
+	// The directory of the log is a file.
+	dir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(dir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	useAuditLog(t, filepath.Join(dir, "audit.log"))
+	if err := RevertAudit(context.Background(), AuditEvent{Action: "debit", UserID: "u1"}); err == nil {
+		t.Error("RevertAudit succeeded without an audit log")
+	}
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.uber.org/zap"
+)
+
+// DefaultBaggageKeys are the baggage members copied into log fields unless
+// LOG_BAGGAGE_KEYS says otherwise.
+var DefaultBaggageKeys = []string{"tenant", "userId", "session", "experiment"}
+
+var baggageKeys = DefaultBaggageKeys
+
+func baggageKeysFromEnv() []string {
+	value, ok := os.LookupEnv("LOG_BAGGAGE_KEYS")
+	if !ok {
+		return DefaultBaggageKeys
+	}
+	var keys []string
+	for _, k := range strings.Split(value, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// SetBaggageKeys replaces the allow-list of baggage members logged as fields.
+func SetBaggageKeys(keys ...string) {
+	baggageKeys = keys
+}
+
+// ContextWithBaggage returns a copy of ctx whose baggage also carries key and
+// value. It is meant for the edge of the system, where the value is known,
+// so that downstream services log it too. Keys which are not logged and
+// values which cannot be propagated leave ctx unchanged.
+func ContextWithBaggage(ctx context.Context, key, value string) context.Context {
+	if value == "" || !isBaggageKey(key) {
+		return ctx
+	}
+	// NewMember takes the value percent-encoded and keeps it decoded, as the
+	// propagator does when it parses the header, so members always hold the
+	// plain value.
+	member, err := baggage.NewMember(key, url.QueryEscape(value))
+	if err != nil {
+		return ctx
+	}
+	// The receiving propagator checks the decoded value against the grammar
+	// of the header and drops members with spaces or non-ASCII characters.
+	if _, err := baggage.Parse(member.String()); err != nil {
+		return ctx
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// BaggageValue returns the value of the baggage member key of ctx, as set by
+// ContextWithBaggage, or "" if ctx has none. It is the value baggageFields
+// logs.
+func BaggageValue(ctx context.Context, key string) string {
+	return baggage.FromContext(ctx).Member(key).Value()
+}
+
+func isBaggageKey(key string) bool {
+	for _, k := range baggageKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// baggageFields appends the allow-listed baggage members of ctx to dst,
+// unless the caller already logs a field with the same key, either in fields
+// or in the logger fields added through With.
+func baggageFields(ctx context.Context, dst, fields, loggerFields []zap.Field) []zap.Field {
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return dst
+	}
+	for _, key := range baggageKeys {
+		member := bag.Member(key)
+		if member.Key() == "" || hasField(fields, key) || hasField(loggerFields, key) {
+			continue
+		}
+		dst = append(dst, zap.String(key, member.Value()))
+	}
+	return dst
+}
+
+func hasField(fields []zap.Field, key string) bool {
+	for i := range fields {
+		if fields[i].Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestBaggageRoundTrip(t *testing.T) {
+	logs := useObservedSink(t)
+	for _, value := range []string{"42", "a+b=c", "100%", "user/7", "O'Neil&co"} {
+		ctx := ContextWithBaggage(context.Background(), "userId", value)
+		if got := BaggageValue(ctx, "userId"); got != value {
+			t.Errorf("BaggageValue before propagation = %q, want %q", got, value)
+		}
+
+		// The calling service injects the header, the called one extracts it.
+		header := http.Header{}
+		propagation.Baggage{}.Inject(ctx, propagation.HeaderCarrier(header))
+		remote := propagation.Baggage{}.Extract(context.Background(), propagation.HeaderCarrier(header))
+
+		if got := BaggageValue(remote, "userId"); got != value {
+			t.Errorf("BaggageValue after propagation of %s = %q, want %q", header.Get("baggage"), got, value)
+		}
+		Ctx(remote).Info("baggage")
+		entries := logs.TakeAll()
+		if len(entries) != 1 {
+			t.Fatalf("got %d entries, want 1", len(entries))
+		}
+		if got := entries[0].ContextMap()["userId"]; got != value {
+			t.Errorf("logged userId = %q, want %q", got, value)
+		}
+	}
+}
+
+func TestContextWithBaggageSkipsValuesItCannotPropagate(t *testing.T) {
+	if got := BaggageValue(ContextWithBaggage(context.Background(), "cardNumber", "4111"), "cardNumber"); got != "" {
+		t.Errorf("unlisted key propagated as %q", got)
+	}
+	for _, value := range []string{"Ann Lee", "zoë"} {
+		if got := BaggageValue(ContextWithBaggage(context.Background(), "userId", value), "userId"); got != "" {
+			t.Errorf("%q would be dropped by the receiver but was set as %q", value, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// callerSkip is the number of LoggerWithCtx frames between the call site and
+// zap: the exported method and log.
+const callerSkip = 2
+
+// CallerPath selects how the caller's file is written.
+type CallerPath string
+
+const (
+	// CallerShort writes the last directory and the file, e.g.
+	// users/users.go:83.
+	CallerShort CallerPath = "short"
+	// CallerModule writes the path relative to the module root, e.g.
+	// users/users.go:83 or logger/logger.go:40. Files outside the module are
+	// written as with CallerShort.
+	CallerModule CallerPath = "module"
+	// CallerFull writes the absolute path.
+	CallerFull CallerPath = "full"
+)
+
+var (
+	// callerEnabled adds the caller to every entry.
+	callerEnabled = true
+	callerPath    = CallerModule
+	// moduleRoot is the directory of the module this package belongs to,
+	// including the trailing slash.
+	moduleRoot = func() string {
+		_, file, _, ok := runtime.Caller(0)
+		if !ok {
+			return ""
+		}
+		return filepath.ToSlash(filepath.Dir(filepath.Dir(file))) + "/"
+	}()
+)
+
+// callerConfigFromEnv reads LOG_CALLER, LOG_CALLER_PATH and
+// LOG_CALLER_FUNCTION into the encoder configuration.
+func callerConfigFromEnv(cfg *zapcore.EncoderConfig) {
+	callerEnabled = envBool("LOG_CALLER", true)
+	if !callerEnabled {
+		cfg.CallerKey = zapcore.OmitKey
+		return
+	}
+	switch path := CallerPath(strings.ToLower(os.Getenv("LOG_CALLER_PATH"))); path {
+	case CallerShort, CallerModule, CallerFull:
+		callerPath = path
+	default:
+		callerPath = CallerModule
+	}
+	cfg.CallerKey = "caller"
+	cfg.EncodeCaller = encodeCaller
+	if envBool("LOG_CALLER_FUNCTION", false) {
+		cfg.FunctionKey = "function"
+	}
+}
+
+func encodeCaller(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(callerString(caller))
+}
+
+// callerString formats caller as file:line according to LOG_CALLER_PATH.
+func callerString(caller zapcore.EntryCaller) string {
+	if !caller.Defined {
+		return "undefined"
+	}
+	switch callerPath {
+	case CallerFull:
+		return caller.FullPath()
+	case CallerModule:
+		if moduleRoot != "/" && strings.HasPrefix(caller.File, moduleRoot) {
+			return strings.TrimPrefix(caller.FullPath(), moduleRoot)
+		}
+	}
+	return caller.TrimmedPath()
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// useObservedSink replaces the local sinks with an observer until the test
+// ends.
+func useObservedSink(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	savedSinks, savedExtra, savedPath := sinkCores, extraCores, callerPath
+	obs, logs := observer.New(zapcore.DebugLevel)
+	sinkCores, extraCores, callerPath = []zapcore.Core{obs}, nil, CallerModule
+	build()
+	t.Cleanup(func() {
+		sinkCores, extraCores, callerPath = savedSinks, savedExtra, savedPath
+		build()
+	})
+	return logs
+}
+
+// nextLine returns the line after the one it is called on.
+func nextLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line + 1
+}
+
+func TestCallerIsCallSite(t *testing.T) {
+	logs := useObservedSink(t)
+	ctx := NewContext(context.Background(), Ctx(context.Background()))
+	l := Ctx(ctx)
+
+	var want []int
+	want = append(want, nextLine())
+	l.Info("info")
+	want = append(want, nextLine())
+	l.Err(errors.New("refused"), "err")
+	want = append(want, nextLine())
+	l.With(zap.String("userId", "42")).Warn("with")
+	want = append(want, nextLine())
+	l.Named("orders").Debug("named")
+	want = append(want, nextLine())
+	FromContext(ctx).Info("from context")
+	want = append(want, nextLine())
+	l.Sugar().Infof("sugar %d", 1)
+	want = append(want, nextLine())
+	l.Sugar().Infow("sugar with", "userId", "42")
+	want = append(want, nextLine())
+	l.Sugar().With("userId", "42").Error("sugar with")
+
+	entries := logs.AllUntimed()
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		wantCaller := fmt.Sprintf("logger/caller_test.go:%d", want[i])
+		if got := callerString(e.Caller); got != wantCaller {
+			t.Errorf("%s: caller = %s, want %s", e.Message, got, wantCaller)
+		}
+	}
+}
+
+func TestCallerGoldenLine(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := zapcore.EncoderConfig{
+		MessageKey:   "message",
+		LevelKey:     "level",
+		CallerKey:    "caller",
+		EncodeLevel:  zapcore.LowercaseLevelEncoder,
+		EncodeCaller: encodeCaller,
+	}
+	savedSinks, savedExtra := sinkCores, extraCores
+	sinkCores = []zapcore.Core{zapcore.NewCore(zapcore.NewJSONEncoder(cfg), zapcore.AddSync(&buf), zapcore.DebugLevel)}
+	extraCores = nil
+	build()
+	t.Cleanup(func() {
+		sinkCores, extraCores = savedSinks, savedExtra
+		build()
+	})
+
+	line := nextLine()
+	Ctx(context.Background()).Sugar().Infof("order %s created", "o-1")
+
+	want := fmt.Sprintf(`{"level":"info","caller":"logger/caller_test.go:%d","message":"order o-1 created"}`+"\n", line)
+	if got := buf.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type requestLoggerKey struct{}
+
+// requestLogger is the request-scoped logger stored in a context. It is
+// shared by every context derived from the request, so fields added by a
+// handler also show up in the logs of the middleware.
+type requestLogger struct {
+	mu     sync.RWMutex
+	logger LoggerWithCtx
+}
+
+// NewContext returns a copy of ctx which carries l as the request-scoped
+// logger.
+func NewContext(ctx context.Context, l LoggerWithCtx) context.Context {
+	return context.WithValue(ctx, requestLoggerKey{}, &requestLogger{logger: l})
+}
+
+// FromContext returns the request-scoped logger stored in ctx, bound to ctx
+// so that spans started after the logger was stored are still correlated.
+// Without a stored logger it is the same as Ctx(ctx).
+func FromContext(ctx context.Context) LoggerWithCtx {
+	rl, ok := ctx.Value(requestLoggerKey{}).(*requestLogger)
+	if !ok {
+		return Ctx(ctx)
+	}
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return LoggerWithCtx{
+		logger: rl.logger.logger,
+		ctx:    ctx,
+		fields: rl.logger.fields,
+	}
+}
+
+// AddFields adds fields to the request-scoped logger stored in ctx, e.g. a
+// userId once the handler has read it. It does nothing if ctx has no
+// request-scoped logger.
+func AddFields(ctx context.Context, fields ...zap.Field) {
+	rl, ok := ctx.Value(requestLoggerKey{}).(*requestLogger)
+	if !ok {
+		return
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.logger = rl.logger.With(fields...)
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFromContextWithoutRequestLogger(t *testing.T) {
+	logs := useObservedSink(t)
+	ctx := trace.ContextWithSpanContext(context.Background(), testSpanContext(t))
+	FromContext(ctx).Info("background job")
+
+	fields := logs.All()[0].ContextMap()
+	if fields["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("fields = %v", fields)
+	}
+}
+
+func TestFromContextBindsTheGivenContext(t *testing.T) {
+	logs := useObservedSink(t)
+	ctx := NewContext(context.Background(), Ctx(context.Background()).With(zap.String("requestId", "req-1")))
+	// A span started after the logger was stored.
+	FromContext(trace.ContextWithSpanContext(ctx, testSpanContext(t))).Info("charging card")
+
+	fields := logs.All()[0].ContextMap()
+	if fields["requestId"] != "req-1" || fields["span_id"] != "00f067aa0ba902b7" {
+		t.Errorf("fields = %v", fields)
+	}
+}
+
+func TestAddFieldsSharedByDerivedContexts(t *testing.T) {
+	logs := useObservedSink(t)
+	ctx := NewContext(context.Background(), Ctx(context.Background()).With(zap.String("requestId", "req-1")))
+	handlerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The handler adds a field to the logger the middleware logs with.
+	AddFields(handlerCtx, zap.String("userId", "42"))
+	FromContext(ctx).Info("request completed")
+
+	fields := logs.All()[0].ContextMap()
+	if fields["requestId"] != "req-1" || fields["userId"] != "42" {
+		t.Errorf("fields = %v", fields)
+	}
+}
+
+func TestAddFieldsWithoutRequestLogger(t *testing.T) {
+	logs := useObservedSink(t)
+	ctx := context.Background()
+	AddFields(ctx, zap.String("userId", "42"))
+	FromContext(ctx).Info("background job")
+
+	if _, ok := logs.All()[0].ContextMap()["userId"]; ok {
+		t.Error("added a field without a request-scoped logger")
+	}
+}
+
+func TestAddFieldsConcurrently(t *testing.T) {
+	useDiscardSink(t, zapcore.DebugLevel)
+	ctx := NewContext(context.Background(), Ctx(context.Background()))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			AddFields(ctx, zap.Int("i", i))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		FromContext(ctx).Debug("polling")
+	}
+	<-done
+}
@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// CorrelationFormatter appends the fields which correlate a log entry with
+// the span sc to fields. Its result is cached per span, so it must only depend
+// on the trace ID, span ID and flags of sc.
+type CorrelationFormatter func(sc trace.SpanContext, fields []zap.Field) []zap.Field
+
+var (
+	formattersMu sync.RWMutex
+	// formatters are the correlation presets selectable through
+	// LOG_CORRELATION_FORMAT.
+	formatters = map[string]func() CorrelationFormatter{
+		"otel":    func() CorrelationFormatter { return otelCorrelation },
+		"w3c":     func() CorrelationFormatter { return otelCorrelation },
+		"datadog": func() CorrelationFormatter { return datadogCorrelation },
+		"xray":    func() CorrelationFormatter { return xrayCorrelation },
+		"gcp":     func() CorrelationFormatter { return gcpCorrelation(os.Getenv("GOOGLE_CLOUD_PROJECT")) },
+		"ecs":     func() CorrelationFormatter { return ecsCorrelation },
+	}
+	correlation CorrelationFormatter = otelCorrelation
+)
+
+// RegisterCorrelationFormatter makes a formatter selectable by name through
+// LOG_CORRELATION_FORMAT.
+func RegisterCorrelationFormatter(name string, f CorrelationFormatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	formatters[name] = func() CorrelationFormatter { return f }
+}
+
+// SetCorrelationFormatter replaces the formatter used by LoggerWithCtx.
+func SetCorrelationFormatter(f CorrelationFormatter) {
+	correlation = f
+	atomic.AddUint64(&correlationGeneration, 1)
+}
+
+// correlationCacheSize is the number of spans whose correlation fields are
+// cached. Spans whose IDs end in the same bits evict each other.
+const correlationCacheSize = 1024
+
+type correlationEntry struct {
+	traceID    trace.TraceID
+	spanID     trace.SpanID
+	flags      trace.TraceFlags
+	generation uint64
+	fields     []zap.Field
+}
+
+var (
+	correlationCache [correlationCacheSize]atomic.Value
+	// correlationGeneration invalidates the cache when the formatter changes.
+	correlationGeneration uint64
+)
+
+// correlationFields returns the correlation fields of sc followed by the
+// field which carries sc to the cores. They are built once per span rather
+// than on every log call and must not be modified.
+func correlationFields(sc trace.SpanContext) []zap.Field {
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	slot := &correlationCache[binary.BigEndian.Uint16(spanID[6:])%correlationCacheSize]
+	generation := atomic.LoadUint64(&correlationGeneration)
+	if e, ok := slot.Load().(*correlationEntry); ok && e.generation == generation &&
+		e.spanID == spanID && e.traceID == traceID && e.flags == sc.TraceFlags() {
+		return e.fields
+	}
+
+	fields := append(correlation(sc, nil), spanContextField(sc))
+	slot.Store(&correlationEntry{
+		traceID:    traceID,
+		spanID:     spanID,
+		flags:      sc.TraceFlags(),
+		generation: generation,
+		fields:     fields,
+	})
+	return fields
+}
+
+// correlationFromEnv returns the formatter named by LOG_CORRELATION_FORMAT,
+// otel by default.
+func correlationFromEnv() (CorrelationFormatter, error) {
+	name := strings.ToLower(os.Getenv("LOG_CORRELATION_FORMAT"))
+	if name == "" {
+		name = "otel"
+	}
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	newFormatter, ok := formatters[name]
+	if !ok {
+		names := make([]string, 0, len(formatters))
+		for n := range formatters {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return otelCorrelation, fmt.Errorf("unknown LOG_CORRELATION_FORMAT %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return newFormatter(), nil
+}
+
+// otelCorrelation follows the field names of the OpenTelemetry log data model.
+func otelCorrelation(sc trace.SpanContext, fields []zap.Field) []zap.Field {
+	return append(fields,
+		zap.String("span_id", sc.SpanID().String()),
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.Int("trace_flags", int(sc.TraceFlags())),
+	)
+}
+
+// datadogCorrelation uses the decimal form of the low 64 bits of the trace ID,
+// which is what Datadog uses to link logs to traces.
+func datadogCorrelation(sc trace.SpanContext, fields []zap.Field) []zap.Field {
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	return append(fields,
+		zap.String("dd.trace_id", strconv.FormatUint(binary.BigEndian.Uint64(traceID[8:]), 10)),
+		zap.String("dd.span_id", strconv.FormatUint(binary.BigEndian.Uint64(spanID[:]), 10)),
+	)
+}
+
+// xrayCorrelation formats the trace ID as 1-<8 hex digits>-<24 hex digits>.
+// It assumes the first 32 bits are an epoch, as generated by the X-Ray ID
+// generator.
+func xrayCorrelation(sc trace.SpanContext, fields []zap.Field) []zap.Field {
+	traceID := sc.TraceID().String()
+	return append(fields,
+		zap.String("xray_trace_id", "1-"+traceID[:8]+"-"+traceID[8:]),
+		zap.String("xray_segment_id", sc.SpanID().String()),
+	)
+}
+
+// gcpCorrelation uses the special fields of Cloud Logging structured logs.
+func gcpCorrelation(project string) CorrelationFormatter {
+	prefix := ""
+	if project != "" {
+		prefix = "projects/" + project + "/traces/"
+	}
+	return func(sc trace.SpanContext, fields []zap.Field) []zap.Field {
+		return append(fields,
+			zap.String("logging.googleapis.com/trace", prefix+sc.TraceID().String()),
+			zap.String("logging.googleapis.com/spanId", sc.SpanID().String()),
+			zap.Bool("logging.googleapis.com/trace_sampled", sc.IsSampled()),
+		)
+	}
+}
+
+// ecsCorrelation follows the Elastic Common Schema.
+func ecsCorrelation(sc trace.SpanContext, fields []zap.Field) []zap.Field {
+	return append(fields,
+		zap.String("trace.id", sc.TraceID().String()),
+		zap.String("span.id", sc.SpanID().String()),
+	)
+}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// testSpanContext is the sampled span of the W3C trace context examples.
+func testSpanContext(t *testing.T) trace.SpanContext {
+	t.Helper()
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+}
+
+// useBufferSink replaces the local sinks with a JSON core writing only the
+// message and fields to the returned buffer until the test ends.
+func useBufferSink(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	savedSinks, savedExtra := sinkCores, extraCores
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "message"})
+	sinkCores = []zapcore.Core{zapcore.NewCore(enc, zapcore.AddSync(&buf), zapcore.DebugLevel)}
+	extraCores = nil
+	build()
+	t.Cleanup(func() {
+		sinkCores, extraCores = savedSinks, savedExtra
+		build()
+	})
+	return &buf
+}
+
+// useCorrelation sets the correlation formatter until the test ends.
+func useCorrelation(t *testing.T, f CorrelationFormatter) {
+	t.Helper()
+	saved := correlation
+	SetCorrelationFormatter(f)
+	t.Cleanup(func() { SetCorrelationFormatter(saved) })
+}
+
+func TestCorrelationPresets(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		formatter CorrelationFormatter
+		want      string
+	}{
+		{
+			"otel", otelCorrelation,
+			`{"message":"paid","span_id":"00f067aa0ba902b7","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","trace_flags":1}`,
+		},
+		{
+			// The low 64 bits of the trace ID and the span ID in decimal.
+			"datadog", datadogCorrelation,
+			`{"message":"paid","dd.trace_id":"11803532876627986230","dd.span_id":"67667974448284343"}`,
+		},
+		{
+			// The first 32 bits are the epoch of the X-Ray ID generator.
+			"xray", xrayCorrelation,
+			`{"message":"paid","xray_trace_id":"1-4bf92f35-77b34da6a3ce929d0e0e4736","xray_segment_id":"00f067aa0ba902b7"}`,
+		},
+		{
+			"gcp", gcpCorrelation("my-project"),
+			`{"message":"paid","logging.googleapis.com/trace":"projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736","logging.googleapis.com/spanId":"00f067aa0ba902b7","logging.googleapis.com/trace_sampled":true}`,
+		},
+		{
+			"gcp without a project", gcpCorrelation(""),
+			`{"message":"paid","logging.googleapis.com/trace":"4bf92f3577b34da6a3ce929d0e0e4736","logging.googleapis.com/spanId":"00f067aa0ba902b7","logging.googleapis.com/trace_sampled":true}`,
+		},
+		{
+			"ecs", ecsCorrelation,
+			`{"message":"paid","trace.id":"4bf92f3577b34da6a3ce929d0e0e4736","span.id":"00f067aa0ba902b7"}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := useBufferSink(t)
+			useCorrelation(t, tt.formatter)
+			ctx := trace.ContextWithSpanContext(context.Background(), testSpanContext(t))
+			Ctx(ctx).Info("paid")
+			if got := buf.String(); got != tt.want+"\n" {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCorrelationFromEnvGCPProject(t *testing.T) {
+	t.Setenv("LOG_CORRELATION_FORMAT", "GCP")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+	f, err := correlationFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := f(testSpanContext(t), nil)
+	if got := fields[0].String; got != "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace = %s", got)
+	}
+
+	t.Setenv("LOG_CORRELATION_FORMAT", "zipkin")
+	if _, err := correlationFromEnv(); err == nil {
+		t.Error("accepted an unknown format")
+	}
+}
+
+func TestCorrelationFieldsAreNotNamespaced(t *testing.T) {
+	buf := useBufferSink(t)
+	useCorrelation(t, ecsCorrelation)
+	ctx := trace.ContextWithSpanContext(context.Background(), testSpanContext(t))
+	Ctx(ctx).Info("paid", zap.Namespace("order"), zap.String("id", "o-1"))
+
+	want := `{"message":"paid","trace.id":"4bf92f3577b34da6a3ce929d0e0e4736","span.id":"00f067aa0ba902b7","order":{"id":"o-1"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
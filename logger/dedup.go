@@ -0,0 +1,260 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DedupConfig controls which entries a DedupCore collapses.
+type DedupConfig struct {
+	// MinLevel is the lowest level which is deduplicated. Entries from
+	// DPanic up are always written, since they panic or exit.
+	MinLevel zapcore.Level
+	// Window is how long repeats of an entry are suppressed after it was
+	// written. A summary entry with the number of repeats follows.
+	Window time.Duration
+	// KeyFields are the fields which, with the level and message, identify
+	// repeats of an entry.
+	KeyFields []string
+	// TraceSamples is the number of trace IDs of suppressed entries kept in
+	// the summary.
+	TraceSamples int
+	// MaxKeys limits the entries tracked at once. Further entries are written
+	// as they are.
+	MaxKeys int
+}
+
+// DefaultDedupConfig collapses errors with the same message and error, as
+// logged by zap.Error or LoggerWithCtx.Err, for ten seconds.
+func DefaultDedupConfig() DedupConfig {
+	return DedupConfig{
+		MinLevel:     zapcore.ErrorLevel,
+		Window:       10 * time.Second,
+		KeyFields:    []string{"error", "error.message"},
+		TraceSamples: 5,
+		MaxKeys:      1000,
+	}
+}
+
+func dedupConfigFromEnv() (DedupConfig, bool) {
+	cfg := DefaultDedupConfig()
+	if level, ok := levelFromEnv("LOG_DEDUP_LEVEL"); ok {
+		cfg.MinLevel = level
+	}
+	cfg.Window = envDuration("LOG_DEDUP_WINDOW", cfg.Window)
+	if value, ok := os.LookupEnv("LOG_DEDUP_KEYS"); ok {
+		cfg.KeyFields = nil
+		for _, k := range strings.Split(value, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				cfg.KeyFields = append(cfg.KeyFields, k)
+			}
+		}
+	}
+	cfg.TraceSamples = envInt("LOG_DEDUP_TRACES", cfg.TraceSamples)
+	cfg.MaxKeys = envInt("LOG_DEDUP_MAX_KEYS", cfg.MaxKeys)
+	return cfg, envBool("LOG_DEDUP", false)
+}
+
+// dedupState is shared by a DedupCore and its clones, and kept when the
+// package logger is rebuilt.
+type dedupState struct {
+	cfg        DedupConfig
+	mu         sync.Mutex
+	entries    map[string]*dedupEntry
+	suppressed uint64
+}
+
+// dedupEntry is an entry which was written and whose repeats are counted
+// until its window ends.
+type dedupEntry struct {
+	// core is the wrapped core without the fields added through With.
+	core     zapcore.Core
+	entry    zapcore.Entry
+	fields   []zapcore.Field
+	repeated int
+	traceIDs []string
+	timer    *time.Timer
+}
+
+// DedupCore writes the first of identical entries and suppresses the repeats
+// inside a window, after which it writes a summary entry such as
+// "connection refused (repeated 532 times)" with the trace IDs of a few
+// suppressed entries. The summary only carries the key fields, not the
+// fields of the first entry's request.
+type DedupCore struct {
+	zapcore.Core
+	// base is the wrapped core without the fields added through With. The
+	// summaries are written to it, since they stand for the entries of many
+	// requests rather than those of the first one.
+	base  zapcore.Core
+	state *dedupState
+	// keyFields are the key fields added through With.
+	keyFields   []zapcore.Field
+	spanContext trace.SpanContext
+}
+
+func NewDedupCore(core zapcore.Core, cfg DedupConfig) *DedupCore {
+	return &DedupCore{
+		Core:  core,
+		base:  core,
+		state: &dedupState{cfg: cfg, entries: map[string]*dedupEntry{}},
+	}
+}
+
+func (c *DedupCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	clone.keyFields = append(append([]zapcore.Field{}, c.keyFields...), c.state.keyFieldsOf(fields)...)
+	if sc, ok := spanContextFromFields(fields); ok {
+		clone.spanContext = sc
+	}
+	return &clone
+}
+
+func (c *DedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *DedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := c.state
+	if ent.Level < s.cfg.MinLevel || ent.Level >= zapcore.DPanicLevel || s.cfg.Window <= 0 {
+		return writeThrough(c.Core, ent, fields)
+	}
+
+	keyFields := append(append([]zapcore.Field{}, c.keyFields...), s.keyFieldsOf(fields)...)
+	key := dedupKey(ent, keyFields)
+
+	s.mu.Lock()
+	if e, ok := s.entries[key]; ok {
+		e.repeated++
+		if sc, ok := c.spanContextOf(fields); ok && len(e.traceIDs) < s.cfg.TraceSamples {
+			e.traceIDs = appendTraceID(e.traceIDs, sc.TraceID().String())
+		}
+		s.mu.Unlock()
+		atomic.AddUint64(&s.suppressed, 1)
+		return nil
+	}
+	if s.cfg.MaxKeys <= 0 || len(s.entries) < s.cfg.MaxKeys {
+		s.entries[key] = &dedupEntry{
+			core:   c.base,
+			entry:  ent,
+			fields: keyFields,
+			timer:  time.AfterFunc(s.cfg.Window, func() { s.flush(key) }),
+		}
+	}
+	s.mu.Unlock()
+	return writeThrough(c.Core, ent, fields)
+}
+
+// Sync writes the summaries of the current windows before syncing the
+// wrapped core.
+func (c *DedupCore) Sync() error {
+	c.state.flushAll()
+	return c.Core.Sync()
+}
+
+// Suppressed returns the number of entries suppressed so far.
+func (c *DedupCore) Suppressed() uint64 {
+	return atomic.LoadUint64(&c.state.suppressed)
+}
+
+func (c *DedupCore) spanContextOf(fields []zapcore.Field) (trace.SpanContext, bool) {
+	if sc, ok := spanContextFromFields(fields); ok {
+		return sc, sc.IsValid()
+	}
+	return c.spanContext, c.spanContext.IsValid()
+}
+
+func (s *dedupState) keyFieldsOf(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for _, f := range fields {
+		for _, k := range s.cfg.KeyFields {
+			if f.Key == k {
+				out = append(out, f)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// flush ends the window of key and writes its summary if it was repeated.
+func (s *dedupState) flush(key string) {
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	if ok {
+		delete(s.entries, key)
+	}
+	s.mu.Unlock()
+	if ok {
+		s.summarize(e)
+	}
+}
+
+func (s *dedupState) flushAll() {
+	s.mu.Lock()
+	entries := s.entries
+	s.entries = map[string]*dedupEntry{}
+	s.mu.Unlock()
+	for _, e := range entries {
+		e.timer.Stop()
+		s.summarize(e)
+	}
+}
+
+func (s *dedupState) summarize(e *dedupEntry) {
+	if e.repeated == 0 {
+		return
+	}
+	ent := e.entry
+	ent.Time = time.Now()
+	ent.Message = fmt.Sprintf("%s (repeated %d times)", ent.Message, e.repeated)
+	// The stacktrace was written with the first entry.
+	ent.Stack = ""
+	fields := append(e.fields,
+		zap.Int("repeated", e.repeated),
+		zap.Duration("window", s.cfg.Window),
+	)
+	if len(e.traceIDs) > 0 {
+		fields = append(fields, zap.Strings("sampled_trace_ids", e.traceIDs))
+	}
+	_ = writeThrough(e.core, ent, fields)
+}
+
+// dedupKey identifies repeats of an entry by its logger, level, message and
+// key fields.
+func dedupKey(ent zapcore.Entry, keyFields []zapcore.Field) string {
+	var b strings.Builder
+	b.WriteString(ent.LoggerName)
+	b.WriteByte(0)
+	b.WriteString(ent.Level.String())
+	b.WriteByte(0)
+	b.WriteString(ent.Message)
+	for _, f := range keyFields {
+		b.WriteByte(0)
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		b.WriteString(fieldString(f))
+	}
+	return b.String()
+}
+
+func appendTraceID(ids []string, id string) []string {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestDedupLogger(window time.Duration) (*zap.Logger, *DedupCore, *observer.ObservedLogs) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	cfg := DefaultDedupConfig()
+	cfg.Window = window
+	core := NewDedupCore(obs, cfg)
+	return zap.New(core), core, logs
+}
+
+func TestDedupCoreSummarizesRepeats(t *testing.T) {
+	l, core, logs := newTestDedupLogger(time.Hour)
+	for i := 0; i < 5; i++ {
+		l.Error("query failed", zap.Error(errors.New("connection refused")))
+	}
+	l.Error("query failed", zap.Error(errors.New("timeout")))
+	l.Warn("below the level of the core")
+	l.Warn("below the level of the core")
+	core.state.flushAll()
+
+	var messages []string
+	for _, e := range logs.All() {
+		messages = append(messages, e.Message)
+	}
+	want := []string{
+		"query failed",
+		"query failed",
+		"below the level of the core",
+		"below the level of the core",
+		"query failed (repeated 4 times)",
+	}
+	if len(messages) != len(want) {
+		t.Fatalf("messages = %q, want %q", messages, want)
+	}
+	for i := range want {
+		if messages[i] != want[i] {
+			t.Errorf("message %d = %q, want %q", i, messages[i], want[i])
+		}
+	}
+	if got := core.Suppressed(); got != 4 {
+		t.Errorf("suppressed %d, want 4", got)
+	}
+}
+
+func TestDedupCoreNeverSuppressesDPanic(t *testing.T) {
+	l, core, logs := newTestDedupLogger(time.Hour)
+	l = l.WithOptions(zap.Development())
+	for i := 0; i < 3; i++ {
+		func() {
+			defer func() { recover() }()
+			l.DPanic("invariant broken", zap.Error(errors.New("nil order")))
+		}()
+	}
+	core.state.flushAll()
+
+	if got := logs.FilterMessage("invariant broken").Len(); got != 3 {
+		t.Errorf("wrote %d DPanic entries, want 3", got)
+	}
+	if got := logs.Len(); got != 3 {
+		t.Errorf("wrote %d entries, want 3 and no summary", got)
+	}
+}
+
+func TestDedupSummaryHasNoRequestFields(t *testing.T) {
+	l, core, logs := newTestDedupLogger(time.Hour)
+	for i, request := range []string{"req-1", "req-2", "req-3"} {
+		sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{byte(i + 1)}, SpanID: trace.SpanID{1}})
+		l.With(zap.String("requestId", request), zap.String("userId", "42"), spanContextField(sc)).
+			Error("query failed", zap.Error(errors.New("connection refused")))
+	}
+	core.state.flushAll()
+
+	summaries := logs.FilterMessage("query failed (repeated 2 times)").AllUntimed()
+	if len(summaries) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(summaries))
+	}
+	fields := summaries[0].ContextMap()
+	for _, key := range []string{"requestId", "userId"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("summary carries %s of the first request", key)
+		}
+	}
+	if fields["error"] != "connection refused" || fields["repeated"] != int64(2) {
+		t.Errorf("summary fields = %v", fields)
+	}
+	want := []interface{}{trace.TraceID{2}.String(), trace.TraceID{3}.String()}
+	if got := fields["sampled_trace_ids"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("sampled_trace_ids = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// devMessageWidth is the column the fields of the dev encoder start at.
+const devMessageWidth = 40
+
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+)
+
+// devProfile reports whether APP_ENV selects the development profile, in
+// which the console sink uses the dev encoder.
+func devProfile() bool {
+	switch strings.ToLower(os.Getenv("APP_ENV")) {
+	case "dev", "development", "local":
+		return true
+	}
+	return false
+}
+
+// devColor colors the output of the dev encoder. SetupLog enables it when
+// stdout is a terminal and NO_COLOR is not set.
+var devColor bool
+
+func devColorFromEnv() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// consoleEncoderName is the default encoder of the console sink.
+func consoleEncoderName() string {
+	if devProfile() {
+		return "dev"
+	}
+	return "console"
+}
+
+// encodeDev writes a line meant to be read in a terminal: colored level,
+// the first 8 characters of the trace and span IDs, the message padded to a
+// column, the fields, the caller and an indented stacktrace. Colors are only
+// added if devColor is set.
+func encodeDev(buf *buffer.Buffer, cfg zapcore.EncoderConfig, rec mapRecord) error {
+	color := func(c, s string) {
+		if !devColor {
+			buf.AppendString(s)
+			return
+		}
+		buf.AppendString(c)
+		buf.AppendString(s)
+		buf.AppendString(colorReset)
+	}
+
+	ent := rec.entry
+	color(colorDim, ent.Time.Format("15:04:05.000"))
+	buf.AppendByte(' ')
+	level := ent.Level.CapitalString()
+	color(levelColor(ent.Level), level)
+	buf.AppendString(strings.Repeat(" ", 5-len(level)+1))
+
+	// The trace is shown by the short IDs instead of the correlation fields.
+	skip := map[string]bool{}
+	if sc := rec.spanContext; sc.IsValid() {
+		traceID, spanID := sc.TraceID().String(), sc.SpanID().String()
+		color(colorDim, traceID[:8]+"/"+spanID[:8])
+		for _, f := range correlationFields(sc) {
+			skip[f.Key] = true
+		}
+	} else {
+		buf.AppendString(strings.Repeat(" ", 17))
+	}
+	buf.AppendByte(' ')
+
+	width := len(ent.Message)
+	if ent.LoggerName != "" {
+		color(colorCyan, ent.LoggerName+": ")
+		width += len(ent.LoggerName) + 2
+	}
+	buf.AppendString(ent.Message)
+	flat := make(map[string]interface{}, len(rec.fields))
+	flatten(flat, "", ".", rec.fields)
+	for k := range skip {
+		delete(flat, k)
+	}
+	if (len(flat) > 0 || ent.Caller.Defined) && width < devMessageWidth {
+		buf.AppendString(strings.Repeat(" ", devMessageWidth-width))
+	}
+	for _, k := range sortedKeys(flat) {
+		buf.AppendByte(' ')
+		color(colorDim, k+"=")
+		buf.AppendString(logfmtValue(flat[k]))
+	}
+
+	if ent.Caller.Defined {
+		buf.AppendByte(' ')
+		color(colorDim, devCaller(ent.Caller))
+	}
+	if ent.Stack != "" {
+		appendDevStack(buf, ent.Stack, color)
+	}
+	return nil
+}
+
+func levelColor(level zapcore.Level) string {
+	switch {
+	case level >= zapcore.ErrorLevel:
+		return colorRed
+	case level == zapcore.WarnLevel:
+		return colorYellow
+	case level == zapcore.InfoLevel:
+		return colorBlue
+	}
+	return colorMagenta
+}
+
+// devCaller formats the caller as file:line and the function without its
+// package path, e.g. users/users.go:83 (main.updateUser.func1).
+func devCaller(caller zapcore.EntryCaller) string {
+	s := callerString(caller)
+	if caller.Function != "" {
+		s += " (" + filepath.Base(caller.Function) + ")"
+	}
+	return s
+}
+
+// appendDevStack writes zap's stacktrace, which alternates function and
+// tab-indented file:line lines, as one indented line per frame.
+func appendDevStack(buf *buffer.Buffer, stack string, color func(c, s string)) {
+	lines := strings.Split(stack, "\n")
+	for i := 0; i < len(lines); i++ {
+		function := strings.TrimSpace(lines[i])
+		if function == "" {
+			continue
+		}
+		location := ""
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			i++
+			location = shortFile(strings.TrimSpace(lines[i]))
+		}
+		buf.AppendString("\n    at ")
+		buf.AppendString(filepath.Base(function))
+		if location != "" {
+			buf.AppendByte(' ')
+			color(colorDim, location)
+		}
+	}
+}
+
+// shortFile keeps the last directory and the file of a path like
+// /src/module/users/users.go:83.
+func shortFile(path string) string {
+	dir, file := filepath.Split(path)
+	if dir == "" {
+		return file
+	}
+	return filepath.Join(filepath.Base(dir), file)
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func encodeDevLine(t *testing.T) string {
+	t.Helper()
+	enc, err := NewEncoder("dev", zap.NewProductionEncoderConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: "low balance"}, []zapcore.Field{zap.Int("amount", 3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+	return buf.String()
+}
+
+func TestDevEncoderColors(t *testing.T) {
+	saved := devColor
+	t.Cleanup(func() { devColor = saved })
+
+	devColor = true
+	if line := encodeDevLine(t); !strings.Contains(line, colorYellow) {
+		t.Errorf("colored line %q has no color codes", line)
+	}
+	devColor = false
+	if line := encodeDevLine(t); strings.Contains(line, "\x1b[") {
+		t.Errorf("plain line %q has color codes", line)
+	}
+}
+
+func TestDevColorFromEnvNeedsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved := os.Stdout
+	os.Stdout = f
+	t.Cleanup(func() { os.Stdout = saved })
+
+	t.Setenv("NO_COLOR", "")
+	os.Unsetenv("NO_COLOR")
+	if devColorFromEnv() {
+		t.Error("colors enabled with stdout redirected to a file")
+	}
+}
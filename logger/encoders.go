@@ -0,0 +1,416 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// EncoderFactory builds an encoder from the shared encoder configuration.
+type EncoderFactory func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]EncoderFactory{
+		"json": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return zapcore.NewJSONEncoder(cfg), nil
+		},
+		"console": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return zapcore.NewConsoleEncoder(cfg), nil
+		},
+		"logfmt": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return newMapEncoder(cfg, encodeLogfmt), nil
+		},
+		"ecs": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return newMapEncoder(cfg, encodeECS), nil
+		},
+		"gelf": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return newMapEncoder(cfg, encodeGELF), nil
+		},
+		"otel": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return newMapEncoder(cfg, encodeOTel), nil
+		},
+		"dev": func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return newMapEncoder(cfg, encodeDev), nil
+		},
+	}
+	bufferPool = buffer.NewPool()
+	hostname   string
+)
+
+func init() {
+	hostname, _ = os.Hostname()
+}
+
+// RegisterEncoder makes an encoder selectable by name for the sinks.
+func RegisterEncoder(name string, factory EncoderFactory) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[name] = factory
+}
+
+// NewEncoder builds the encoder registered under name: json, console,
+// logfmt, ecs, gelf, otel, dev or one added through RegisterEncoder.
+func NewEncoder(name string, cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	encodersMu.RLock()
+	factory, ok := encoders[strings.ToLower(name)]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown log encoder %q", name)
+	}
+	return factory(cfg)
+}
+
+// sinkEncoder builds the encoder named by LOG_<SINK>_ENCODER, falling back to
+// fallback if it is unset or unknown.
+func sinkEncoder(sink, fallback string, cfg zapcore.EncoderConfig) zapcore.Encoder {
+	name := os.Getenv("LOG_" + strings.ToUpper(sink) + "_ENCODER")
+	if name != "" {
+		enc, err := NewEncoder(name, cfg)
+		if err == nil {
+			return enc
+		}
+		fmt.Fprintf(os.Stderr, "%s sink: %v\n", sink, err)
+	}
+	enc, _ := NewEncoder(fallback, cfg)
+	return enc
+}
+
+// mapRecord is an entry whose fields have been collected into a map, the
+// input of the formats which are not built on zap's JSON encoder.
+type mapRecord struct {
+	entry       zapcore.Entry
+	fields      map[string]interface{}
+	spanContext trace.SpanContext
+}
+
+// mapEncoder collects the fields of an entry with a zapcore.MapObjectEncoder
+// and hands them to a format function.
+type mapEncoder struct {
+	*zapcore.MapObjectEncoder
+	cfg         zapcore.EncoderConfig
+	format      func(buf *buffer.Buffer, cfg zapcore.EncoderConfig, rec mapRecord) error
+	spanContext trace.SpanContext
+}
+
+func newMapEncoder(cfg zapcore.EncoderConfig, format func(*buffer.Buffer, zapcore.EncoderConfig, mapRecord) error) *mapEncoder {
+	return &mapEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		cfg:              cfg,
+		format:           format,
+	}
+}
+
+// attributes returns the fields of rec without the resource and correlation
+// fields, for the formats which have fields of their own for those.
+func (rec mapRecord) attributes() map[string]interface{} {
+	attrs := make(map[string]interface{}, len(rec.fields))
+	for k, v := range rec.fields {
+		attrs[k] = v
+	}
+	resourceMu.RLock()
+	for _, kv := range resourceAttrs {
+		delete(attrs, string(kv.Key))
+	}
+	resourceMu.RUnlock()
+	if rec.spanContext.IsValid() {
+		for _, f := range correlationFields(rec.spanContext) {
+			delete(attrs, f.Key)
+		}
+	}
+	return attrs
+}
+
+func (e *mapEncoder) Clone() zapcore.Encoder {
+	clone := newMapEncoder(e.cfg, e.format)
+	clone.spanContext = e.spanContext
+	for k, v := range e.Fields {
+		clone.Fields[k] = copyValue(v)
+	}
+	return clone
+}
+
+// copyValue copies the namespaces, which a MapObjectEncoder keeps as maps.
+func copyValue(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = copyValue(v)
+	}
+	return c
+}
+
+func (e *mapEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := e.Clone().(*mapEncoder)
+	for _, f := range fields {
+		if sc, ok := f.Interface.(trace.SpanContext); ok && f.Key == spanContextKey && f.Type == zapcore.SkipType {
+			enc.spanContext = sc
+			continue
+		}
+		f.AddTo(enc)
+	}
+
+	buf := bufferPool.Get()
+	if err := e.format(buf, e.cfg, mapRecord{entry: ent, fields: enc.Fields, spanContext: enc.spanContext}); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	buf.AppendString(e.lineEnding())
+	return buf, nil
+}
+
+func (e *mapEncoder) lineEnding() string {
+	if e.cfg.LineEnding == "" {
+		return zapcore.DefaultLineEnding
+	}
+	return e.cfg.LineEnding
+}
+
+func appendJSON(buf *buffer.Buffer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode log entry error: %w", err)
+	}
+	buf.Write(b)
+	return nil
+}
+
+// flatten adds the nested maps of fields to out with joined keys.
+func flatten(out map[string]interface{}, prefix, sep string, fields map[string]interface{}) {
+	for k, v := range fields {
+		if m, ok := v.(map[string]interface{}); ok {
+			flatten(out, prefix+k+sep, sep, m)
+			continue
+		}
+		out[prefix+k] = v
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// encodeLogfmt writes key=value pairs, using the configured keys for the
+// entry itself and dotted keys for nested fields.
+func encodeLogfmt(buf *buffer.Buffer, cfg zapcore.EncoderConfig, rec mapRecord) error {
+	first := true
+	pair := func(key string, value interface{}) {
+		if key == "" || key == zapcore.OmitKey {
+			return
+		}
+		if !first {
+			buf.AppendByte(' ')
+		}
+		first = false
+		buf.AppendString(logfmtKey(key))
+		buf.AppendByte('=')
+		buf.AppendString(logfmtValue(value))
+	}
+
+	ent := rec.entry
+	pair(cfg.TimeKey, ent.Time.Format(time.RFC3339Nano))
+	pair(cfg.LevelKey, ent.Level.String())
+	if ent.LoggerName != "" {
+		pair(cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		pair(cfg.CallerKey, callerString(ent.Caller))
+		if ent.Caller.Function != "" {
+			pair(cfg.FunctionKey, ent.Caller.Function)
+		}
+	}
+	pair(cfg.MessageKey, ent.Message)
+
+	flat := make(map[string]interface{}, len(rec.fields))
+	flatten(flat, "", ".", rec.fields)
+	for _, k := range sortedKeys(flat) {
+		pair(k, flat[k])
+	}
+	if ent.Stack != "" {
+		pair(cfg.StacktraceKey, ent.Stack)
+	}
+	return nil
+}
+
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtValue(v interface{}) string {
+	switch v.(type) {
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	s := stringify(v)
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n\\") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// stringify returns strings as is and the JSON encoding of anything else.
+func stringify(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// encodeECS writes Elastic Common Schema JSON. The trace is written as
+// trace.id and span.id whatever the correlation format.
+func encodeECS(buf *buffer.Buffer, cfg zapcore.EncoderConfig, rec mapRecord) error {
+	ent := rec.entry
+	doc := rec.attributes()
+	doc["@timestamp"] = ent.Time.UTC().Format(time.RFC3339Nano)
+	doc["log.level"] = ent.Level.String()
+	doc["message"] = ent.Message
+	doc["ecs.version"] = "1.6.0"
+	if ent.LoggerName != "" {
+		doc["log.logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		doc["log.origin.file.name"] = ent.Caller.File
+		doc["log.origin.file.line"] = ent.Caller.Line
+		if ent.Caller.Function != "" {
+			doc["log.origin.function"] = ent.Caller.Function
+		}
+	}
+	if ent.Stack != "" {
+		doc["error.stack_trace"] = ent.Stack
+	}
+	if rec.spanContext.IsValid() {
+		doc["trace.id"] = rec.spanContext.TraceID().String()
+		doc["span.id"] = rec.spanContext.SpanID().String()
+	}
+	for k, v := range resourceMap() {
+		doc[k] = v
+	}
+	return appendJSON(buf, doc)
+}
+
+// gelfLevels maps zap levels to the syslog severities used by GELF.
+var gelfLevels = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  2,
+	zapcore.FatalLevel:  2,
+}
+
+// encodeGELF writes a GELF 1.1 message. Fields become additional fields
+// prefixed with an underscore.
+func encodeGELF(buf *buffer.Buffer, cfg zapcore.EncoderConfig, rec mapRecord) error {
+	ent := rec.entry
+	doc := map[string]interface{}{
+		"version":       "1.1",
+		"host":          hostname,
+		"short_message": ent.Message,
+		"timestamp":     math.Round(float64(ent.Time.UnixNano())/1e6) / 1e3,
+		"level":         gelfLevels[ent.Level],
+	}
+	if ent.Stack != "" {
+		doc["full_message"] = ent.Message + "\n" + ent.Stack
+	}
+
+	additional := make(map[string]interface{}, len(rec.fields)+4)
+	flatten(additional, "", "_", rec.fields)
+	if ent.LoggerName != "" {
+		additional["logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		additional["file"] = ent.Caller.File
+		additional["line"] = ent.Caller.Line
+	}
+	for k, v := range additional {
+		key := "_" + gelfKey(k)
+		if key == "_id" {
+			key = "_id_"
+		}
+		switch v.(type) {
+		case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			doc[key] = v
+		default:
+			// GELF only allows strings and numbers.
+			doc[key] = stringify(v)
+		}
+	}
+	return appendJSON(buf, doc)
+}
+
+func gelfKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '.' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// encodeOTel writes the JSON form of the OpenTelemetry log data model. The
+// resource and trace are only written to their top-level fields.
+func encodeOTel(buf *buffer.Buffer, cfg zapcore.EncoderConfig, rec mapRecord) error {
+	ent := rec.entry
+	attrs := rec.attributes()
+	if ent.LoggerName != "" {
+		attrs["logger.name"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		attrs["code.filepath"] = ent.Caller.File
+		attrs["code.lineno"] = ent.Caller.Line
+		if ent.Caller.Function != "" {
+			attrs["code.function"] = ent.Caller.Function
+		}
+	}
+	if ent.Stack != "" {
+		attrs["exception.stacktrace"] = ent.Stack
+	}
+
+	doc := map[string]interface{}{
+		"Timestamp":            strconv.FormatInt(ent.Time.UnixNano(), 10),
+		"ObservedTimestamp":    strconv.FormatInt(time.Now().UnixNano(), 10),
+		"SeverityText":         ent.Level.CapitalString(),
+		"SeverityNumber":       int32(severityNumber(ent.Level)),
+		"Body":                 ent.Message,
+		"Resource":             resourceMap(),
+		"InstrumentationScope": map[string]interface{}{"Name": instrumentationName},
+		"Attributes":           attrs,
+	}
+	if rec.spanContext.IsValid() {
+		doc["TraceId"] = rec.spanContext.TraceID().String()
+		doc["SpanId"] = rec.spanContext.SpanID().String()
+		doc["TraceFlags"] = int(rec.spanContext.TraceFlags())
+	}
+	return appendJSON(buf, doc)
+}
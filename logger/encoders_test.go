@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// useResource sets the resource attributes until the test ends.
+func useResource(t *testing.T, attrs ...attribute.KeyValue) {
+	t.Helper()
+	resourceMu.Lock()
+	saved := resourceAttrs
+	resourceAttrs = attrs
+	resourceMu.Unlock()
+	t.Cleanup(func() {
+		resourceMu.Lock()
+		resourceAttrs = saved
+		resourceMu.Unlock()
+	})
+}
+
+// encodeTestEntry encodes an entry with the fields the logger adds to a
+// handler's log call: the resource, the correlation fields of the otel
+// preset and the caller's fields.
+func encodeTestEntry(t *testing.T, name string) string {
+	t.Helper()
+	useResource(t, attribute.String("service.name", "orders"))
+	useCorrelation(t, otelCorrelation)
+	savedHost, savedColor := hostname, devColor
+	hostname, devColor = "host-1", false
+	t.Cleanup(func() { hostname, devColor = savedHost, savedColor })
+
+	enc, err := NewEncoder(name, zapcore.EncoderConfig{TimeKey: "time", LevelKey: "level", MessageKey: "message"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc = enc.Clone()
+	zap.String("service.name", "orders").AddTo(enc)
+
+	fields := append([]zap.Field{}, correlationFields(testSpanContext(t))...)
+	fields = append(fields, zap.String("userId", "42"), zap.Int("amount", 3))
+	ent := zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Date(2026, 10, 17, 8, 0, 0, 123000000, time.UTC),
+		Message: "order paid",
+	}
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+	return buf.String()
+}
+
+func TestEncoderGoldenLines(t *testing.T) {
+	for name, want := range map[string]string{
+		"logfmt": `time=2026-10-17T08:00:00.123Z level=info message="order paid" amount=3 service.name=orders span_id=00f067aa0ba902b7 trace_flags=1 trace_id=4bf92f3577b34da6a3ce929d0e0e4736 userId=42`,
+		// Only trace.id and span.id, and the resource once.
+		"ecs":  `{"@timestamp":"2026-10-17T08:00:00.123Z","amount":3,"ecs.version":"1.6.0","log.level":"info","message":"order paid","service.name":"orders","span.id":"00f067aa0ba902b7","trace.id":"4bf92f3577b34da6a3ce929d0e0e4736","userId":"42"}`,
+		"gelf": `{"_amount":3,"_service.name":"orders","_span_id":"00f067aa0ba902b7","_trace_flags":1,"_trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","_userId":"42","host":"host-1","level":6,"short_message":"order paid","timestamp":1792224000.123,"version":"1.1"}`,
+		"dev":  `08:00:00.123 INFO  4bf92f35/00f067aa order paid                               amount=3 service.name=orders userId=42`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := encodeTestEntry(t, name); got != want+"\n" {
+				t.Errorf("got  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestOTelEncoderKeepsResourceAndTraceOutOfAttributes(t *testing.T) {
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(encodeTestEntry(t, "otel")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["ObservedTimestamp"]; !ok {
+		t.Error("no ObservedTimestamp")
+	}
+	delete(got, "ObservedTimestamp")
+
+	want := map[string]interface{}{
+		"Timestamp":            "1792224000123000000",
+		"SeverityText":         "INFO",
+		"SeverityNumber":       float64(9),
+		"Body":                 "order paid",
+		"Resource":             map[string]interface{}{"service.name": "orders"},
+		"InstrumentationScope": map[string]interface{}{"Name": instrumentationName},
+		"Attributes":           map[string]interface{}{"amount": float64(3), "userId": "42"},
+		"TraceId":              "4bf92f3577b34da6a3ce929d0e0e4736",
+		"SpanId":               "00f067aa0ba902b7",
+		"TraceFlags":           float64(1),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// levelFromEnv parses the level in the key environment variable. Unlike
+// zapcore.ParseLevel it does not treat an unset variable as info.
+func levelFromEnv(key string) (zapcore.Level, bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return zapcore.InfoLevel, false
+	}
+	level, err := zapcore.ParseLevel(value)
+	return level, err == nil
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+func envBool(key string, fallback bool) bool {
+	if v, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
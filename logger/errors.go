@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// errorKey is the key of the skip field which carries the error of Err to
+// the span.
+const errorKey = "otel.error"
+
+// errorStacks adds the stack of errors which carry one, like those of
+// github.com/pkg/errors, to the log entry and the exception event.
+var errorStacks = true
+
+// Err logs err at error level with its type, message, the chain of wrapped
+// errors and, if it carries one, its stack. The error is also recorded on the
+// span as an exception event.
+func (l LoggerWithCtx) Err(err error, msg string, fields ...zap.Field) {
+	if err != nil {
+		fields = append(append([]zap.Field{}, fields...), errorFields(err)...)
+	}
+	l.log(zapcore.ErrorLevel, msg, fields)
+}
+
+// errorDetails describes an error the way the OpenTelemetry exception
+// semantic conventions do.
+type errorDetails struct {
+	typ     string
+	message string
+	// chain lists the wrapped errors as "type: message".
+	chain []string
+	stack string
+}
+
+// loggedError is carried by the skip field of Err, so that the span event
+// reuses the details of the log entry.
+type loggedError struct {
+	err     error
+	details errorDetails
+}
+
+func describeError(err error) errorDetails {
+	d := errorDetails{typ: errorType(err), message: err.Error()}
+	for _, cause := range unwrapAll(err) {
+		d.chain = append(d.chain, errorType(cause)+": "+cause.Error())
+	}
+	if errorStacks {
+		if _, ok := err.(fmt.Formatter); ok {
+			if verbose := fmt.Sprintf("%+v", err); verbose != d.message {
+				d.stack = verbose
+			}
+		}
+	}
+	return d
+}
+
+// errorFields returns the error.* fields of err and the field which carries
+// it to the span.
+func errorFields(err error) []zap.Field {
+	d := describeError(err)
+	fields := []zap.Field{
+		zap.String("error.type", d.typ),
+		zap.String("error.message", d.message),
+	}
+	if len(d.chain) > 0 {
+		fields = append(fields, zap.Strings("error.chain", d.chain))
+	}
+	if d.stack != "" {
+		fields = append(fields, zap.String("error.stack_trace", d.stack))
+	}
+	return append(fields, zap.Field{Key: errorKey, Type: zapcore.SkipType, Interface: loggedError{err, d}})
+}
+
+// errorFromFields returns the error passed to Err and its details, or else
+// the first zap.Error field.
+func errorFromFields(fields []zap.Field) (error, errorDetails, bool) {
+	for _, f := range fields {
+		if e, ok := f.Interface.(loggedError); ok && f.Key == errorKey && f.Type == zapcore.SkipType {
+			return e.err, e.details, true
+		}
+	}
+	for _, f := range fields {
+		if e, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType {
+			return e, describeError(e), true
+		}
+	}
+	return nil, errorDetails{}, false
+}
+
+// recordException adds an exception event for err to span and marks it as
+// failed. The event only has a stack if the error carries one, since the
+// stack of the logger would not show where the error happened.
+func recordException(span trace.Span, err error, d errorDetails, msg string) {
+	opts := []trace.EventOption{}
+	if len(d.chain) > 0 {
+		opts = append(opts, trace.WithAttributes(attribute.StringSlice("exception.chain", d.chain)))
+	}
+	if d.stack != "" {
+		opts = append(opts, trace.WithAttributes(attribute.String("exception.stacktrace", d.stack)))
+	}
+	span.RecordError(err, opts...)
+	span.SetStatus(codes.Error, msg)
+}
+
+// unwrapAll returns the errors wrapped by err, depth first.
+func unwrapAll(err error) []error {
+	var causes []error
+	var walk func(error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			if cause := e.Unwrap(); cause != nil {
+				causes = append(causes, cause)
+				walk(cause)
+			}
+		case interface{ Unwrap() []error }:
+			for _, cause := range e.Unwrap() {
+				if cause != nil {
+					causes = append(causes, cause)
+					walk(cause)
+				}
+			}
+		}
+	}
+	walk(err)
+	return causes
+}
+
+// errorType returns the package qualified type name of err, as the
+// OpenTelemetry SDK does for exception.type.
+func errorType(err error) string {
+	t := reflect.TypeOf(err)
+	if t.PkgPath() == "" && t.Name() == "" {
+		// Likely a pointer or a builtin type.
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
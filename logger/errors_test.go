@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// stackError carries a stack which it prints with %+v, like the errors of
+// github.com/pkg/errors.
+type stackError struct{ msg string }
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) Format(s fmt.State, verb rune) {
+	io.WriteString(s, e.msg)
+	if verb == 'v' && s.Flag('+') {
+		io.WriteString(s, "\nmain.pay\n\t/src/main.go:12")
+	}
+}
+
+// recordSpan runs f with the context of a recording span and returns the
+// span once it ended.
+func recordSpan(t *testing.T, f func(ctx context.Context)) sdktrace.ReadOnlySpan {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	ctx, span := tp.Tracer("test").Start(context.Background(), "POST /orders")
+	f(ctx)
+	span.End()
+	return rec.Ended()[0]
+}
+
+func exceptionEvent(t *testing.T, span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	t.Helper()
+	for _, ev := range span.Events() {
+		if ev.Name == "exception" {
+			attrs := map[attribute.Key]attribute.Value{}
+			for _, kv := range ev.Attributes {
+				attrs[kv.Key] = kv.Value
+			}
+			return attrs
+		}
+	}
+	t.Fatal("no exception event")
+	return nil
+}
+
+func TestErrFields(t *testing.T) {
+	logs := useObservedSink(t)
+	err := fmt.Errorf("pay order: %w", fmt.Errorf("charge card: %w", errors.New("connection refused")))
+	Ctx(context.Background()).Err(err, "order failed")
+
+	fields := logs.All()[0].ContextMap()
+	if got := fields["error.type"]; got != "*fmt.wrapError" {
+		t.Errorf("error.type = %v", got)
+	}
+	if got := fields["error.message"]; got != "pay order: charge card: connection refused" {
+		t.Errorf("error.message = %v", got)
+	}
+	wantChain := []interface{}{
+		"*fmt.wrapError: charge card: connection refused",
+		"*errors.errorString: connection refused",
+	}
+	if got := fields["error.chain"]; !reflect.DeepEqual(got, wantChain) {
+		t.Errorf("error.chain = %v, want %v", got, wantChain)
+	}
+	if _, ok := fields["error.stack_trace"]; ok {
+		t.Error("added a stack to an error which carries none")
+	}
+	if _, ok := fields[errorKey]; ok {
+		t.Errorf("logged the %s skip field", errorKey)
+	}
+}
+
+func TestErrFieldsStackOfError(t *testing.T) {
+	logs := useObservedSink(t)
+	Ctx(context.Background()).Err(&stackError{"card declined"}, "order failed")
+
+	fields := logs.All()[0].ContextMap()
+	if got := fields["error.stack_trace"]; got != "card declined\nmain.pay\n\t/src/main.go:12" {
+		t.Errorf("error.stack_trace = %q", got)
+	}
+	if got := fields["error.type"]; got != "*logger.stackError" {
+		t.Errorf("error.type = %v", got)
+	}
+}
+
+func TestErrRecordsExceptionEvent(t *testing.T) {
+	useDiscardSink(t, zapcore.DebugLevel)
+	withSpanEvents(t, true)
+	err := fmt.Errorf("pay order: %w", errors.New("card declined"))
+	span := recordSpan(t, func(ctx context.Context) {
+		Ctx(ctx).Err(err, "order failed")
+	})
+
+	if span.Status().Code != codes.Error || span.Status().Description != "order failed" {
+		t.Errorf("status = %+v", span.Status())
+	}
+	attrs := exceptionEvent(t, span)
+	if got := attrs["exception.type"].AsString(); got != "*fmt.wrapError" {
+		t.Errorf("exception.type = %s", got)
+	}
+	if got := attrs["exception.message"].AsString(); got != "pay order: card declined" {
+		t.Errorf("exception.message = %s", got)
+	}
+	if got := attrs["exception.chain"].AsStringSlice(); !reflect.DeepEqual(got, []string{"*errors.errorString: card declined"}) {
+		t.Errorf("exception.chain = %q", got)
+	}
+	// The stack of the logger would not show where the error happened.
+	if _, ok := attrs["exception.stacktrace"]; ok {
+		t.Error("added a stack to an error which carries none")
+	}
+}
+
+func TestErrRecordsStackOfError(t *testing.T) {
+	useDiscardSink(t, zapcore.DebugLevel)
+	withSpanEvents(t, true)
+	span := recordSpan(t, func(ctx context.Context) {
+		Ctx(ctx).Err(&stackError{"card declined"}, "order failed")
+	})
+
+	attrs := exceptionEvent(t, span)
+	if got := attrs["exception.stacktrace"].AsString(); got != "card declined\nmain.pay\n\t/src/main.go:12" {
+		t.Errorf("exception.stacktrace = %q", got)
+	}
+}
+
+func TestErrorLevelRecordsZapError(t *testing.T) {
+	useDiscardSink(t, zapcore.DebugLevel)
+	withSpanEvents(t, true)
+	span := recordSpan(t, func(ctx context.Context) {
+		Ctx(ctx).Error("order failed", zap.Error(&stackError{"card declined"}))
+	})
+
+	attrs := exceptionEvent(t, span)
+	if got := attrs["exception.message"].AsString(); got != "card declined" {
+		t.Errorf("exception.message = %s", got)
+	}
+	if _, ok := attrs["exception.stacktrace"]; !ok {
+		t.Error("no stack for a zap.Error field which carries one")
+	}
+}
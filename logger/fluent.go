@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// FluentConfig configures a FluentWriter.
+type FluentConfig struct {
+	// Network is tcp or unix.
+	Network string
+	Addr    string
+	Tag     string
+	// RequireAck waits for the receiver to acknowledge every entry and
+	// resends it once over a new connection if it does not. It costs a round
+	// trip per entry, so it is off by default.
+	RequireAck bool
+	Timeout    time.Duration
+}
+
+// FluentWriter sends entries to Fluentd or Fluent Bit with the Fluent Forward
+// protocol in message mode. Entries encoded as a JSON object are sent as
+// structured records, anything else under the "message" key.
+type FluentWriter struct {
+	cfg    FluentConfig
+	conn   *reconnectingConn
+	reader *bufio.Reader
+}
+
+func NewFluentWriter(cfg FluentConfig) *FluentWriter {
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	w := &FluentWriter{cfg: cfg, conn: newReconnectingConn(cfg.Network, cfg.Addr, cfg.Timeout)}
+	w.conn.onConnect = func(c net.Conn) { w.reader = bufio.NewReader(c) }
+	return w
+}
+
+func (w *FluentWriter) WriteEntry(ent zapcore.Entry, p []byte) error {
+	message := []interface{}{w.cfg.Tag, eventTime(ent.Time), fluentRecord(p)}
+	var chunk string
+	if w.cfg.RequireAck {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return fmt.Errorf("generate fluent chunk id error: %w", err)
+		}
+		chunk = base64.StdEncoding.EncodeToString(id)
+		message = append(message, map[string]interface{}{"chunk": chunk})
+	}
+	msg := appendMsgpack(nil, message)
+
+	w.conn.mu.Lock()
+	defer w.conn.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if err := w.conn.writeLocked(msg); err != nil || !w.cfg.RequireAck || w.conn.conn == nil {
+			return err
+		}
+		err := w.readAck(chunk)
+		if err == nil {
+			return nil
+		}
+		w.conn.closeLocked()
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+func (w *FluentWriter) readAck(chunk string) error {
+	w.conn.conn.SetReadDeadline(time.Now().Add(w.conn.timeout))
+	resp, err := readMsgpackStringMap(w.reader)
+	if err != nil {
+		return fmt.Errorf("read fluent ack error: %w", err)
+	}
+	if resp["ack"] != chunk {
+		return fmt.Errorf("fluent ack mismatch: got %q, want %q", resp["ack"], chunk)
+	}
+	return nil
+}
+
+// fluentRecord decodes an entry encoded as a JSON object into a record.
+func fluentRecord(p []byte) map[string]interface{} {
+	p = bytes.TrimSpace(p)
+	if len(p) > 0 && p[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(p))
+		dec.UseNumber()
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err == nil {
+			return record
+		}
+	}
+	return map[string]interface{}{"message": string(p)}
+}
+
+func (w *FluentWriter) Sync() error {
+	return nil
+}
+
+func (w *FluentWriter) Close() error {
+	return w.conn.Close()
+}
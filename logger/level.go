@@ -0,0 +1,343 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelRegistry holds the runtime-adjustable levels of every sink and of the
+// named loggers. Overrides set with a TTL revert on their own.
+type levelRegistry struct {
+	mu      sync.RWMutex
+	sinks   map[string]zap.AtomicLevel
+	loggers map[string]zap.AtomicLevel
+	// reverts holds the pending TTL reverts keyed by levelKey.
+	reverts map[string]*levelRevert
+}
+
+type levelRevert struct {
+	timer     *time.Timer
+	to        *zapcore.Level // nil removes a named logger override
+	expiresAt time.Time
+}
+
+var levels = &levelRegistry{
+	sinks:   map[string]zap.AtomicLevel{},
+	loggers: map[string]zap.AtomicLevel{},
+	reverts: map[string]*levelRevert{},
+}
+
+// SinkLevel returns the level of the named sink. A new sink starts at
+// LOG_<NAME>_LEVEL, LOG_LEVEL or debug, whichever is set first.
+func SinkLevel(name string) zap.AtomicLevel {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if level, ok := levels.sinks[name]; ok {
+		return level
+	}
+	level := zap.NewAtomicLevelAt(envLevel(name))
+	levels.sinks[name] = level
+	return level
+}
+
+// envLevel reads the configured level of the named sink.
+func envLevel(name string) zapcore.Level {
+	for _, key := range []string{"LOG_" + strings.ToUpper(name) + "_LEVEL", "LOG_LEVEL"} {
+		if level, ok := levelFromEnv(key); ok {
+			return level
+		}
+	}
+	return zapcore.DebugLevel
+}
+
+// cancelReverts stops the pending TTL reverts, so that they do not overwrite
+// the levels applied by a new SetupLog.
+func (r *levelRegistry) cancelReverts() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, revert := range r.reverts {
+		revert.timer.Stop()
+		delete(r.reverts, key)
+	}
+}
+
+// loggerLevelsFromEnv applies LOG_LOGGER_LEVELS, e.g. "db=info,http.client=warn".
+func loggerLevelsFromEnv() {
+	for _, pair := range strings.Split(os.Getenv("LOG_LOGGER_LEVELS"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		level, err := zapcore.ParseLevel(value)
+		if err != nil {
+			continue
+		}
+		if err := levels.set("", name, level, 0); err != nil {
+			fmt.Fprintf(os.Stderr, "LOG_LOGGER_LEVELS: %v\n", err)
+		}
+	}
+}
+
+// SetSinkLevel changes the level of a sink. A positive ttl reverts the change
+// once it expires.
+func SetSinkLevel(sink string, level zapcore.Level, ttl time.Duration) error {
+	return levels.set(sink, "", level, ttl)
+}
+
+// SetLoggerLevel changes the level of the logger with the given name and of
+// its children. A positive ttl reverts the change once it expires. The
+// entries of the logger still go through the sink levels, so it can only be
+// made stricter than the sinks: a level below every sink is rejected, lower
+// the level of a sink first.
+func SetLoggerLevel(name string, level zapcore.Level, ttl time.Duration) error {
+	return levels.set("", name, level, ttl)
+}
+
+func levelKey(sink, logger string) string {
+	if sink != "" {
+		return "sink:" + sink
+	}
+	return "logger:" + logger
+}
+
+func (r *levelRegistry) set(sink, logger string, level zapcore.Level, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		current zap.AtomicLevel
+		exists  bool
+	)
+	if sink != "" {
+		if current, exists = r.sinks[sink]; !exists {
+			return fmt.Errorf("unknown sink %q", sink)
+		}
+	} else {
+		if lowest, ok := r.lowestSinkLevel(); ok && level < lowest {
+			return fmt.Errorf("level %s of logger %q is below the level of every sink (%s), lower a sink level first", level, logger, lowest)
+		}
+		current, exists = r.loggers[logger]
+	}
+
+	key := levelKey(sink, logger)
+	revert, pending := r.reverts[key]
+	if pending {
+		revert.timer.Stop()
+		delete(r.reverts, key)
+	}
+	if ttl > 0 {
+		// Revert to the level in place before the first pending override.
+		if !pending {
+			revert = &levelRevert{}
+			if exists {
+				previous := current.Level()
+				revert.to = &previous
+			}
+		}
+		revert.expiresAt = time.Now().Add(ttl)
+		revert.timer = time.AfterFunc(ttl, func() { r.revert(sink, logger, revert) })
+		r.reverts[key] = revert
+	}
+
+	if !exists {
+		current = zap.NewAtomicLevel()
+		r.loggers[logger] = current
+	}
+	current.SetLevel(level)
+	return nil
+}
+
+// lowestSinkLevel returns the level of the most verbose sink, or false if
+// there are no sinks.
+func (r *levelRegistry) lowestSinkLevel() (zapcore.Level, bool) {
+	lowest, ok := zapcore.InvalidLevel, false
+	for _, level := range r.sinks {
+		if l := level.Level(); !ok || l < lowest {
+			lowest, ok = l, true
+		}
+	}
+	return lowest, ok
+}
+
+func (r *levelRegistry) revert(sink, logger string, revert *levelRevert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := levelKey(sink, logger)
+	if r.reverts[key] != revert {
+		return
+	}
+	delete(r.reverts, key)
+	switch {
+	case sink != "":
+		r.sinks[sink].SetLevel(*revert.to)
+	case revert.to == nil:
+		delete(r.loggers, logger)
+	default:
+		r.loggers[logger].SetLevel(*revert.to)
+	}
+}
+
+// loggerLevel returns the level of the named logger or of its closest
+// configured parent, e.g. "db" for "db.mongo".
+func (r *levelRegistry) loggerLevel(name string) (zap.AtomicLevel, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.loggers) == 0 {
+		return zap.AtomicLevel{}, false
+	}
+	for name != "" {
+		if level, ok := r.loggers[name]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return zap.AtomicLevel{}, false
+}
+
+// namedLevelCore drops the entries of named loggers below their configured
+// level before they reach the sinks.
+type namedLevelCore struct {
+	zapcore.Core
+}
+
+func (c namedLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return namedLevelCore{c.Core.With(fields)}
+}
+
+func (c namedLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.LoggerName != "" {
+		if level, ok := levels.loggerLevel(ent.LoggerName); ok && !level.Enabled(ent.Level) {
+			return ce
+		}
+	}
+	return c.Core.Check(ent, ce)
+}
+
+type levelState struct {
+	Level     string     `json:"level"`
+	RevertTo  string     `json:"revertTo,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type levelsResponse struct {
+	Sinks   map[string]levelState `json:"sinks"`
+	Loggers map[string]levelState `json:"loggers"`
+}
+
+type levelRequest struct {
+	// Sink and Logger select what to change. When both are empty every sink
+	// is changed.
+	Sink   string `json:"sink"`
+	Logger string `json:"logger"`
+	Level  string `json:"level"`
+	// TTL is a duration such as "10m" after which the change is reverted.
+	TTL string `json:"ttl"`
+}
+
+func (r *levelRegistry) snapshot() levelsResponse {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	state := func(key string, level zap.AtomicLevel) levelState {
+		s := levelState{Level: level.String()}
+		if revert, ok := r.reverts[key]; ok {
+			expiresAt := revert.expiresAt
+			s.ExpiresAt = &expiresAt
+			if revert.to != nil {
+				s.RevertTo = revert.to.String()
+			}
+		}
+		return s
+	}
+	resp := levelsResponse{Sinks: map[string]levelState{}, Loggers: map[string]levelState{}}
+	for name, level := range r.sinks {
+		resp.Sinks[name] = state(levelKey(name, ""), level)
+	}
+	for name, level := range r.loggers {
+		resp.Loggers[name] = state(levelKey("", name), level)
+	}
+	return resp
+}
+
+func (r *levelRegistry) sinkNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.sinks))
+	for name := range r.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LevelHandler serves the sink and logger levels on GET and changes them on
+// PUT with a body such as {"sink": "console", "level": "debug", "ttl": "10m"}.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			if err := putLevel(r); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, levels.snapshot())
+	})
+}
+
+func putLevel(r *http.Request) error {
+	var req levelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("json unmarshal error: %w", err)
+	}
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			return fmt.Errorf("invalid ttl: %w", err)
+		}
+	}
+
+	switch {
+	case req.Sink != "" && req.Logger != "":
+		return fmt.Errorf("set either sink or logger, not both")
+	case req.Logger != "":
+		return SetLoggerLevel(req.Logger, level, ttl)
+	case req.Sink != "":
+		return SetSinkLevel(req.Sink, level, ttl)
+	}
+	for _, sink := range levels.sinkNames() {
+		if err := SetSinkLevel(sink, level, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// useLevels replaces the level registry with one holding sinks at the given
+// levels until the test ends.
+func useLevels(t *testing.T, sinks map[string]zapcore.Level) {
+	t.Helper()
+	saved := levels
+	levels = &levelRegistry{
+		sinks:   map[string]zap.AtomicLevel{},
+		loggers: map[string]zap.AtomicLevel{},
+		reverts: map[string]*levelRevert{},
+	}
+	for name, level := range sinks {
+		levels.sinks[name] = zap.NewAtomicLevelAt(level)
+	}
+	t.Cleanup(func() {
+		levels.cancelReverts()
+		levels = saved
+	})
+}
+
+func putLevelRequest(body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(body)))
+	return w
+}
+
+func TestLoggerLevelCannotGoBelowSinks(t *testing.T) {
+	useLevels(t, map[string]zapcore.Level{"console": zapcore.InfoLevel, "file": zapcore.WarnLevel})
+
+	if w := putLevelRequest(`{"logger": "db", "level": "debug"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("debug below every sink: status %d, want 400", w.Code)
+	}
+	if _, ok := levels.loggerLevel("db"); ok {
+		t.Error("rejected level was applied")
+	}
+
+	if w := putLevelRequest(`{"logger": "db", "level": "info"}`); w.Code != http.StatusOK {
+		t.Errorf("info: status %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := putLevelRequest(`{"sink": "console", "level": "debug"}`); w.Code != http.StatusOK {
+		t.Errorf("sink debug: status %d, want 200", w.Code)
+	}
+	if err := SetLoggerLevel("db", zapcore.DebugLevel, 0); err != nil {
+		t.Errorf("debug once a sink is at debug: %v", err)
+	}
+}
+
+func TestNamedLoggerLevel(t *testing.T) {
+	useLevels(t, map[string]zapcore.Level{"console": zapcore.DebugLevel})
+	logs := useObservedSink(t)
+	if err := SetLoggerLevel("db", zapcore.WarnLevel, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	l := Ctx(context.Background()).Named("db").Named("mongo")
+	l.Info("dropped")
+	l.Warn("kept")
+	Ctx(context.Background()).Named("http").Info("other logger")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 || entries[0].Message != "kept" || entries[1].Message != "other logger" {
+		t.Errorf("entries = %v", entries)
+	}
+}
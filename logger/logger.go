@@ -2,84 +2,352 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sync"
 
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// spanContextKey is the key of the skip field which carries the span context
+// of a log call to cores that need more than the encoded IDs.
+const spanContextKey = "otel.span_context"
+
 var (
-	logger *zap.Logger
+	// logger discards every entry until SetupLog runs.
+	logger = zap.NewNop()
+	// sinkCores are the local outputs built by SetupLog.
+	sinkCores []zapcore.Core
+	// extraCores are the cores registered through AddCore.
+	extraCores []zapcore.Core
+	shutdowns  []func(context.Context) error
+	// sinkClosers release the files and connections of the sink cores.
+	sinkClosers []io.Closer
+	// sampler is the sampling core in front of every sink, if enabled.
+	sampler *SamplingCore
+	// dedup collapses repeated entries, if enabled.
+	dedup *DedupCore
+	// redactor scrubs the fields of every entry and span event, if enabled.
+	redactor *Redactor
+	// tail holds back the entries of requests in flight, if enabled.
+	tail *tailRegistry
+	// fileQueue moves writes to the log file off the logging goroutine, if
+	// enabled.
+	fileQueue *AsyncWriter
 )
 
-func SetupLog() {
+// SetupLog builds the package logger from the LOG_* environment variables.
+// Services call it with their name once they have loaded their .env file;
+// until then the package logger discards every entry. Importing the package
+// opens no files and starts no goroutines.
+func SetupLog(serviceName string) {
 	encoderCfg := zap.NewProductionEncoderConfig()
 	encoderCfg.TimeKey = "time"
 	encoderCfg.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02T15:04:05")
 	encoderCfg.MessageKey = "message"
-	encoderCfg.CallerKey = zapcore.OmitKey
+	callerConfigFromEnv(&encoderCfg)
+
+	fileEncoder := sinkEncoder("file", "json", encoderCfg)
+	consoleEncoder := sinkEncoder("console", consoleEncoderName(), encoderCfg)
+
+	fileLevel, consoleLevel := SinkLevel("file"), SinkLevel("console")
+	levels.cancelReverts()
+	fileLevel.SetLevel(envLevel("file"))
+	consoleLevel.SetLevel(envLevel("console"))
+	loggerLevelsFromEnv()
+	spanEvents = spanEventsFromEnv()
+	errorStacks = envBool("LOG_ERROR_STACK", true)
+	devColor = devColorFromEnv()
+	baggageKeys = baggageKeysFromEnv()
+	resourceMu.Lock()
+	resourceKeys = resourceKeysFromEnv()
+	resourceMu.Unlock()
+	if f, err := correlationFromEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		SetCorrelationFormatter(f)
+	}
+	if cfg, ok := tailConfigFromEnv(); ok {
+		tail = newTailRegistry(cfg)
+	} else {
+		tail = nil
+	}
+	if cfg, ok := redactionConfigFromEnv(); ok {
+		redactor = NewRedactor(cfg)
+	} else {
+		redactor = nil
+	}
+	if policy, ok := samplingPolicyFromEnv(); ok {
+		sampler = NewSamplingCore(nil, policy)
+	} else {
+		sampler = nil
+	}
+	if dedup != nil {
+		// Write the summaries of the old configuration before its sinks close.
+		dedup.state.flushAll()
+	}
+	if cfg, ok := dedupConfigFromEnv(); ok {
+		dedup = NewDedupCore(nil, cfg)
+	} else {
+		dedup = nil
+	}
 
-	fileEncoder := zapcore.NewJSONEncoder(encoderCfg)
-	consoleEncoder := zapcore.NewConsoleEncoder(encoderCfg)
+	auditConfigFromEnv(serviceName)
+
+	closeSinks()
+	fileQueue = nil
+	sinkCores = []zapcore.Core{
+		zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), consoleLevel),
+	}
+	writer, err := NewRotatingWriter(rotateConfigFromEnv(serviceName))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "file logging disabled: %v\n", err)
+	} else {
+		writer.ReopenOnSignal()
+		var out zapcore.WriteSyncer = writer
+		if size, policy, ok := asyncConfigFromEnv(); ok {
+			fileQueue = NewAsyncWriter(writer, size, policy)
+			out = fileQueue
+		}
+		sinkClosers = append(sinkClosers, out.(io.Closer))
+		sinkCores = append(sinkCores, zapcore.NewCore(fileEncoder, out, fileLevel))
+	}
+	for _, sink := range networkSinksFromEnv(serviceName, encoderCfg) {
+		sinkClosers = append(sinkClosers, sink.out)
+		sinkCores = append(sinkCores, sink)
+	}
+	build()
+}
 
-	logFile, _ := os.OpenFile("application.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	writer := zapcore.AddSync(logFile)
-	defaultLogLevel := zapcore.DebugLevel
-	core := zapcore.NewTee(
-		zapcore.NewCore(fileEncoder, writer, defaultLogLevel),
-		zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), defaultLogLevel),
+// closeSinks closes the sinks built by the previous SetupLog.
+func closeSinks() error {
+	var err error
+	for _, c := range sinkClosers {
+		err = multierr.Append(err, c.Close())
+	}
+	sinkClosers = nil
+	return err
+}
+
+// rotateConfigFromEnv reads the file sink configuration. The file defaults to
+// LOG_DIR/<serviceName>.log so that services started from the same directory
+// do not share a file.
+func rotateConfigFromEnv(serviceName string) RotateConfig {
+	filename := os.Getenv("LOG_FILE")
+	if filename == "" {
+		name := "application"
+		if serviceName != "" {
+			name = serviceName
+		}
+		filename = filepath.Join(os.Getenv("LOG_DIR"), name+".log")
+	}
+	return RotateConfig{
+		Filename:   filename,
+		MaxSizeMB:  envInt("LOG_MAX_SIZE_MB", 100),
+		MaxAge:     envDuration("LOG_MAX_AGE", 0),
+		MaxBackups: envInt("LOG_MAX_BACKUPS", 10),
+		Compress:   envBool("LOG_COMPRESS", true),
+	}
+}
+
+// build assembles the package logger from the sink cores and the cores
+// registered through AddCore.
+func build() {
+	// Cores added through AddCore, like the OTLP exporter, carry the resource
+	// themselves.
+	sinks := zapcore.NewTee(sinkCores...)
+	if fields := resourceFields(); len(fields) > 0 {
+		sinks = sinks.With(fields)
+	}
+	var core zapcore.Core = zapcore.NewTee(append([]zapcore.Core{sinks}, extraCores...)...)
+	if tail != nil {
+		core = &TailCore{Core: core, registry: tail}
+	}
+	if dedup != nil {
+		// Keep the windows in progress when cores are added.
+		dedup = &DedupCore{Core: core, base: core, state: dedup.state}
+		core = dedup
+	}
+	if sampler != nil {
+		// Keep the drop counters when cores are added.
+		sampler = &SamplingCore{Core: core, policy: sampler.policy, counters: sampler.counters}
+		core = sampler
+	}
+	if redactor != nil {
+		core = NewRedactionCore(core, redactor)
+	}
+	// The caller and stacktrace start at the call site rather than in
+	// LoggerWithCtx.
+	logger = zap.New(namedLevelCore{core},
+		zap.WithCaller(callerEnabled),
+		zap.AddCallerSkip(callerSkip),
+		zap.AddStacktrace(zapcore.ErrorLevel),
 	)
-	logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 }
 
-func init() {
-	SetupLog()
+// AddCore tees every log entry to core in addition to the local sinks. If the
+// core has a Shutdown(context.Context) error method it is called by Shutdown.
+func AddCore(core zapcore.Core) {
+	extraCores = append(extraCores, core)
+	if s, ok := core.(interface {
+		Shutdown(context.Context) error
+	}); ok {
+		shutdowns = append(shutdowns, s.Shutdown)
+	}
+	build()
+}
+
+// Shutdown flushes the logger and shuts down the cores registered through
+// AddCore. It should be called once before the service exits.
+func Shutdown(ctx context.Context) error {
+	// Syncing stdout fails on most terminals, so only the shutdown errors are
+	// reported.
+	_ = logger.Sync()
+	var err error
+	for _, shutdown := range shutdowns {
+		err = multierr.Append(err, shutdown(ctx))
+	}
+	return multierr.Combine(err, closeAudit(), closeSinks())
 }
 
+// LoggerWithCtx logs with the trace and baggage fields of the context it is
+// bound to. It deliberately does not expose the underlying *zap.Logger, so
+// every call is correlated. It is a small value type, so that binding a
+// context does not allocate.
 type LoggerWithCtx struct {
-	*zap.Logger
-	context *context.Context
+	logger *zap.Logger
+	ctx    context.Context
+	// fields are the fields added through With, kept for span events.
+	fields []zap.Field
+}
+
+func Ctx(ctx context.Context) LoggerWithCtx {
+	return LoggerWithCtx{
+		logger: logger,
+		ctx:    ctx,
+	}
+}
+
+// fieldsPool holds the field slices of log calls in flight. Cores which keep
+// fields after Write returns, like TailCore, copy them.
+var fieldsPool = sync.Pool{
+	New: func() interface{} {
+		fields := make([]zap.Field, 0, 16)
+		return &fields
+	},
+}
+
+// logFields appends the trace and baggage fields of the context to dst,
+// followed by fields. The correlation fields come first, so that a
+// zap.Namespace among fields cannot nest them.
+func (l LoggerWithCtx) logFields(dst, fields []zap.Field) []zap.Field {
+	// Spans of unsampled traces do not record but still carry valid IDs,
+	// which the sampling core needs to see.
+	context := trace.SpanContextFromContext(l.ctx)
+	if context.IsValid() {
+		dst = append(dst, correlationFields(context)...)
+		openTail(l.ctx, context.TraceID())
+	}
+
+	dst = baggageFields(l.ctx, dst, fields, l.fields)
+
+	return append(dst, fields...)
+}
+
+// spanContextField carries sc to the cores without being encoded.
+func spanContextField(sc trace.SpanContext) zap.Field {
+	return zap.Field{Key: spanContextKey, Type: zapcore.SkipType, Interface: sc}
+}
+
+// enabled reports whether an entry at lvl would be written or recorded as a
+// span event, so that callers can skip formatting it. Entries from DPanic up
+// are always built since they panic or exit.
+func (l LoggerWithCtx) enabled(lvl zapcore.Level) bool {
+	if lvl >= zapcore.DPanicLevel || l.logger.Core().Enabled(lvl) {
+		return true
+	}
+	cfg := spanEvents
+	return cfg.Enabled && lvl >= cfg.MinLevel && trace.SpanFromContext(l.ctx).IsRecording()
+}
+
+func (l LoggerWithCtx) log(lvl zapcore.Level, msg string, fields []zap.Field) {
+	recordSpanEvent(trace.SpanFromContext(l.ctx), lvl, msg, l.fields, fields)
+	ce := l.logger.Check(lvl, msg)
+	if ce == nil {
+		return
+	}
+	buf := fieldsPool.Get().(*[]zap.Field)
+	all := l.logFields((*buf)[:0], fields)
+	ce.Write(all...)
+	// Drop the references held by the fields before the slice is reused.
+	for i := range all {
+		all[i] = zap.Field{}
+	}
+	*buf = all[:0]
+	fieldsPool.Put(buf)
 }
 
-func Ctx(ctx context.Context) *LoggerWithCtx {
-	return &LoggerWithCtx{
-		Logger:  logger,
-		context: &ctx,
+// With returns a logger bound to the same context which adds fields to every
+// entry.
+func (l LoggerWithCtx) With(fields ...zap.Field) LoggerWithCtx {
+	if len(fields) == 0 {
+		return l
+	}
+	return LoggerWithCtx{
+		logger: l.logger.With(fields...),
+		ctx:    l.ctx,
+		fields: append(append([]zap.Field{}, l.fields...), fields...),
 	}
 }
 
-func (l *LoggerWithCtx) logFields(
-	ctx context.Context, fields []zap.Field,
-) []zap.Field {
-	span := trace.SpanFromContext(ctx)
-	if span.IsRecording() {
-		context := span.SpanContext()
-		spanField := zap.String("span_id", context.SpanID().String())
-		traceField := zap.String("trace_id", context.TraceID().String())
-		traceFlags := zap.Int("trace_flags", int(context.TraceFlags()))
-		fields = append(fields, []zap.Field{spanField, traceField, traceFlags}...)
+// Named returns a child logger bound to the same context, see zap.Logger.Named.
+func (l LoggerWithCtx) Named(name string) LoggerWithCtx {
+	return LoggerWithCtx{
+		logger: l.logger.Named(name),
+		ctx:    l.ctx,
+		fields: l.fields,
 	}
+}
+
+// Context returns the context the logger is bound to.
+func (l LoggerWithCtx) Context() context.Context {
+	return l.ctx
+}
+
+// Sync flushes any buffered log entries.
+func (l LoggerWithCtx) Sync() error {
+	return l.logger.Sync()
+}
+
+func (l LoggerWithCtx) Debug(msg string, fields ...zap.Field) {
+	l.log(zapcore.DebugLevel, msg, fields)
+}
+
+func (l LoggerWithCtx) Info(msg string, fields ...zap.Field) {
+	l.log(zapcore.InfoLevel, msg, fields)
+}
 
-	return fields
+func (l LoggerWithCtx) Warn(msg string, fields ...zap.Field) {
+	l.log(zapcore.WarnLevel, msg, fields)
 }
 
-func (log *LoggerWithCtx) Info(msg string, fields ...zap.Field) {
-	fieldsWithTraceCtx := log.logFields(*log.context, fields)
-	log.Logger.Info(msg, fieldsWithTraceCtx...)
+func (l LoggerWithCtx) Error(msg string, fields ...zap.Field) {
+	l.log(zapcore.ErrorLevel, msg, fields)
 }
 
-func (log *LoggerWithCtx) Warn(msg string, fields ...zap.Field) {
-	fieldsWithTraceCtx := log.logFields(*log.context, fields)
-	log.Logger.Warn(msg, fieldsWithTraceCtx...)
+func (l LoggerWithCtx) DPanic(msg string, fields ...zap.Field) {
+	l.log(zapcore.DPanicLevel, msg, fields)
 }
 
-func (log *LoggerWithCtx) Error(msg string, fields ...zap.Field) {
-	fieldsWithTraceCtx := log.logFields(*log.context, fields)
-	log.Logger.Error(msg, fieldsWithTraceCtx...)
+func (l LoggerWithCtx) Panic(msg string, fields ...zap.Field) {
+	l.log(zapcore.PanicLevel, msg, fields)
 }
 
-func (log *LoggerWithCtx) Fatal(msg string, fields ...zap.Field) {
-	fieldsWithTraceCtx := log.logFields(*log.context, fields)
-	log.Logger.Fatal(msg, fieldsWithTraceCtx...)
+func (l LoggerWithCtx) Fatal(msg string, fields ...zap.Field) {
+	l.log(zapcore.FatalLevel, msg, fields)
 }
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// useDiscardSink replaces the local sinks with a JSON core writing to
+// io.Discard at level until the test ends.
+func useDiscardSink(tb testing.TB, level zapcore.Level) {
+	tb.Helper()
+	savedSinks, savedExtra := sinkCores, extraCores
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	sinkCores = []zapcore.Core{zapcore.NewCore(enc, zapcore.AddSync(io.Discard), level)}
+	extraCores = nil
+	build()
+	tb.Cleanup(func() {
+		sinkCores, extraCores = savedSinks, savedExtra
+		build()
+	})
+}
+
+// withSpanEvents sets whether log calls are recorded as span events until the
+// test ends.
+func withSpanEvents(tb testing.TB, enabled bool) {
+	saved := spanEvents
+	cfg := spanEvents
+	cfg.Enabled = enabled
+	SetSpanEvents(cfg)
+	tb.Cleanup(func() { SetSpanEvents(saved) })
+}
+
+// handlerContext returns the context of a request handler: a recording span
+// and a request-scoped logger.
+func handlerContext(tb testing.TB) context.Context {
+	tp := sdktrace.NewTracerProvider()
+	tb.Cleanup(func() { tp.Shutdown(context.Background()) })
+	ctx, span := tp.Tracer("bench").Start(context.Background(), "GET /users/{id}")
+	tb.Cleanup(func() { span.End() })
+	return NewContext(ctx, Ctx(ctx).With(zap.String("requestId", "req-1")))
+}
+
+// legacyLoggerWithCtx is the implementation of LoggerWithCtx before it became
+// a value type, kept to compare the benchmarks against.
+type legacyLoggerWithCtx struct {
+	*zap.Logger
+	context *context.Context
+}
+
+func legacyCtx(ctx context.Context) *legacyLoggerWithCtx {
+	return &legacyLoggerWithCtx{Logger: logger, context: &ctx}
+}
+
+func (l *legacyLoggerWithCtx) logFields(ctx context.Context, fields []zap.Field) []zap.Field {
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		context := span.SpanContext()
+		spanField := zap.String("span_id", context.SpanID().String())
+		traceField := zap.String("trace_id", context.TraceID().String())
+		traceFlags := zap.Int("trace_flags", int(context.TraceFlags()))
+		fields = append(fields, []zap.Field{spanField, traceField, traceFlags}...)
+	}
+	return fields
+}
+
+func (l *legacyLoggerWithCtx) Info(msg string, fields ...zap.Field) {
+	l.Logger.Info(msg, l.logFields(*l.context, fields)...)
+}
+
+func TestCtxDoesNotAllocate(t *testing.T) {
+	ctx := handlerContext(t)
+	if allocs := testing.AllocsPerRun(100, func() { _ = Ctx(ctx) }); allocs != 0 {
+		t.Errorf("Ctx allocates %v times, want 0", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _ = FromContext(ctx) }); allocs != 0 {
+		t.Errorf("FromContext allocates %v times, want 0", allocs)
+	}
+}
+
+// The benchmarks log the way the handlers do: bind the request context, then
+// log with a field or two. The legacy implementation did not record span
+// events, so they are compared with span events off.
+
+func BenchmarkHandlerInfo(b *testing.B) {
+	useDiscardSink(b, zapcore.DebugLevel)
+	withSpanEvents(b, false)
+	ctx := handlerContext(b)
+	b.Run("current", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			FromContext(ctx).Info("user fetched", zap.String("userId", "42"))
+		}
+	})
+	b.Run("legacy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			legacyCtx(ctx).Info("user fetched", zap.String("userId", "42"))
+		}
+	})
+}
+
+func BenchmarkHandlerInfoSpanEvents(b *testing.B) {
+	useDiscardSink(b, zapcore.DebugLevel)
+	withSpanEvents(b, true)
+	ctx := handlerContext(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FromContext(ctx).Info("user fetched", zap.String("userId", "42"))
+	}
+}
+
+func BenchmarkHandlerDisabledDebug(b *testing.B) {
+	useDiscardSink(b, zapcore.InfoLevel)
+	ctx := handlerContext(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FromContext(ctx).Debug("user fetched", zap.String("userId", "42"))
+	}
+}
@@ -0,0 +1,298 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	minRedialDelay = 100 * time.Millisecond
+	maxRedialDelay = 30 * time.Second
+)
+
+// entryWriter receives the encoded entries of a sinkCore. Unlike a
+// zapcore.WriteSyncer it also gets the entry, e.g. for the syslog severity.
+type entryWriter interface {
+	WriteEntry(ent zapcore.Entry, p []byte) error
+	Sync() error
+	Close() error
+}
+
+// sinkCore encodes entries with its own encoder and level and hands them to
+// an entryWriter.
+type sinkCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out entryWriter
+}
+
+func newSinkCore(enc zapcore.Encoder, out entryWriter, level zapcore.LevelEnabler) *sinkCore {
+	return &sinkCore{LevelEnabler: level, enc: enc, out: out}
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	err = c.out.WriteEntry(ent, buf.Bytes())
+	buf.Free()
+	return err
+}
+
+func (c *sinkCore) Sync() error {
+	return c.out.Sync()
+}
+
+func (c *sinkCore) Shutdown(ctx context.Context) error {
+	return c.out.Close()
+}
+
+// errReconnecting is returned while a redial is backed off.
+var errReconnecting = errors.New("waiting to reconnect")
+
+// reconnectingConn is a connection which is redialled with exponential
+// backoff after a failure.
+type reconnectingConn struct {
+	network, addr string
+	timeout       time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	delay   time.Duration
+	retryAt time.Time
+	// dropped counts the writes skipped while the redial was backed off.
+	dropped uint64
+	// onConnect runs on every new connection, e.g. to reset a read buffer.
+	onConnect func(net.Conn)
+}
+
+func newReconnectingConn(network, addr string, timeout time.Duration) *reconnectingConn {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &reconnectingConn{network: network, addr: addr, timeout: timeout}
+}
+
+// connect returns the open connection or dials a new one. It must be called
+// with mu held.
+func (c *reconnectingConn) connect() (net.Conn, error) {
+	if c.conn != nil {
+		return c.conn, nil
+	}
+	if time.Now().Before(c.retryAt) {
+		atomic.AddUint64(&c.dropped, 1)
+		return nil, errReconnecting
+	}
+	conn, err := net.DialTimeout(c.network, c.addr, c.timeout)
+	if err != nil {
+		if c.delay == 0 {
+			c.delay = minRedialDelay
+		} else if c.delay *= 2; c.delay > maxRedialDelay {
+			c.delay = maxRedialDelay
+		}
+		c.retryAt = time.Now().Add(c.delay)
+		return nil, fmt.Errorf("dial %s %s error: %w", c.network, c.addr, err)
+	}
+	c.conn, c.delay = conn, 0
+	if c.onConnect != nil {
+		c.onConnect(conn)
+	}
+	return conn, nil
+}
+
+// write sends p, reconnecting once if a stream connection was broken.
+// Entries written while the redial is backed off are dropped silently, so a
+// down receiver does not flood stderr.
+func (c *reconnectingConn) write(p []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writeLocked(p)
+}
+
+func (c *reconnectingConn) writeLocked(p []byte) error {
+	for attempt := 0; ; attempt++ {
+		conn, err := c.connect()
+		if errors.Is(err, errReconnecting) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		conn.SetWriteDeadline(time.Now().Add(c.timeout))
+		if _, err = conn.Write(p); err == nil {
+			return nil
+		}
+		c.closeLocked()
+		if attempt > 0 || isDatagram(c.network) {
+			return fmt.Errorf("write %s %s error: %w", c.network, c.addr, err)
+		}
+	}
+}
+
+func (c *reconnectingConn) closeLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *reconnectingConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+func isDatagram(network string) bool {
+	return strings.HasPrefix(network, "udp") || network == "unixgram"
+}
+
+// parseAddr splits an address such as tcp://host:port, udp://host:port or
+// unix:///dev/log into network and address. An address without a scheme is
+// on defaultNetwork, and a scheme which is not one of networks is an error.
+func parseAddr(addr, defaultNetwork string, networks ...string) (string, string, error) {
+	i := strings.Index(addr, "://")
+	if i < 0 {
+		return defaultNetwork, addr, nil
+	}
+	network := strings.ToLower(addr[:i])
+	for _, n := range networks {
+		if n == network {
+			return network, addr[i+3:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unsupported scheme %s:// in %q, expected one of %s", network, addr, strings.Join(networks, ", "))
+}
+
+// TCPWriter writes newline-delimited entries to a TCP endpoint and
+// reconnects when the connection breaks. Over UDP each entry is a datagram.
+type TCPWriter struct {
+	conn *reconnectingConn
+}
+
+func NewTCPWriter(addr string, timeout time.Duration) *TCPWriter {
+	return newLineWriter("tcp", addr, timeout)
+}
+
+func newLineWriter(network, addr string, timeout time.Duration) *TCPWriter {
+	return &TCPWriter{conn: newReconnectingConn(network, addr, timeout)}
+}
+
+func (w *TCPWriter) Write(p []byte) (int, error) {
+	if err := w.conn.write(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *TCPWriter) WriteEntry(ent zapcore.Entry, p []byte) error {
+	_, err := w.Write(p)
+	return err
+}
+
+func (w *TCPWriter) Sync() error {
+	return nil
+}
+
+func (w *TCPWriter) Close() error {
+	return w.conn.Close()
+}
+
+// Dropped returns the number of entries dropped while reconnecting.
+func (w *TCPWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.conn.dropped)
+}
+
+// networkSinksFromEnv builds the network sinks whose address is configured:
+// LOG_SYSLOG_ADDR, LOG_TCP_ADDR and LOG_FLUENT_ADDR. Each has its own
+// LOG_<SINK>_LEVEL and LOG_<SINK>_ENCODER.
+//
+// The sinks write from a queue of LOG_NETWORK_QUEUE_SIZE entries, so a slow
+// or unreachable receiver never blocks the caller. Entries are dropped while
+// the queue is full. A sink whose address has an unsupported scheme is
+// reported on stderr and left out.
+func networkSinksFromEnv(serviceName string, encoderCfg zapcore.EncoderConfig) []*sinkCore {
+	var sinks []*sinkCore
+	timeout := envDuration("LOG_NETWORK_TIMEOUT", 5*time.Second)
+	queueSize := envInt("LOG_NETWORK_QUEUE_SIZE", 4096)
+	async := func(w entryWriter) entryWriter {
+		return newAsyncEntryWriter(w, queueSize, OverflowDropNewest)
+	}
+
+	if addr := os.Getenv("LOG_SYSLOG_ADDR"); addr != "" {
+		network, address, err := parseAddr(addr, "udp", "udp", "tcp", "unix", "unixgram")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "LOG_SYSLOG_ADDR: %v\n", err)
+		} else {
+			level := SinkLevel("syslog")
+			level.SetLevel(envLevel("syslog"))
+			w := NewSyslogWriter(SyslogConfig{
+				Network:  network,
+				Addr:     address,
+				AppName:  serviceName,
+				Facility: envInt("LOG_SYSLOG_FACILITY", 1),
+				Timeout:  timeout,
+			})
+			sinks = append(sinks, newSinkCore(sinkEncoder("syslog", "json", encoderCfg), async(w), level))
+		}
+	}
+	if addr := os.Getenv("LOG_TCP_ADDR"); addr != "" {
+		network, address, err := parseAddr(addr, "tcp", "tcp", "udp")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "LOG_TCP_ADDR: %v\n", err)
+		} else {
+			level := SinkLevel("tcp")
+			level.SetLevel(envLevel("tcp"))
+			sinks = append(sinks, newSinkCore(sinkEncoder("tcp", "json", encoderCfg), async(newLineWriter(network, address, timeout)), level))
+		}
+	}
+	if addr := os.Getenv("LOG_FLUENT_ADDR"); addr != "" {
+		network, address, err := parseAddr(addr, "tcp", "tcp", "unix")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "LOG_FLUENT_ADDR: %v\n", err)
+		} else {
+			tag := os.Getenv("LOG_FLUENT_TAG")
+			if tag == "" {
+				tag = serviceName
+			}
+			level := SinkLevel("fluent")
+			level.SetLevel(envLevel("fluent"))
+			w := NewFluentWriter(FluentConfig{
+				Network:    network,
+				Addr:       address,
+				Tag:        tag,
+				RequireAck: envBool("LOG_FLUENT_ACK", false),
+				Timeout:    timeout,
+			})
+			sinks = append(sinks, newSinkCore(sinkEncoder("fluent", "json", encoderCfg), async(w), level))
+		}
+	}
+	return sinks
+}
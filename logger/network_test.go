@@ -0,0 +1,408 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// listenTCP accepts connections on a local port until the test ends and hands
+// each one to serve.
+func listenTCP(t *testing.T, serve func(net.Conn)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+func newTestEncoder() zapcore.Encoder {
+	return zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", LevelKey: "level", EncodeLevel: zapcore.LowercaseLevelEncoder})
+}
+
+func TestTCPSinkWritesLines(t *testing.T) {
+	lines := make(chan string, 10)
+	addr := listenTCP(t, func(conn net.Conn) {
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	})
+
+	out := newAsyncEntryWriter(NewTCPWriter(addr, time.Second), 16, OverflowDropNewest)
+	l := zap.New(newSinkCore(newTestEncoder(), out, zapcore.InfoLevel))
+	l.Info("first", zap.String("requestId", "req-1"))
+	l.Warn("second")
+	l.Debug("below the level of the sink")
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"level":"info","msg":"first","requestId":"req-1"}`,
+		`{"level":"warn","msg":"second"}`,
+	}
+	for _, w := range want {
+		select {
+		case got := <-lines:
+			if got != w {
+				t.Errorf("got line %s, want %s", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", w)
+		}
+	}
+}
+
+func TestSyslogSinkSendsRFC5424(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	w := NewSyslogWriter(SyslogConfig{Addr: conn.LocalAddr().String(), AppName: "orders", Facility: 16})
+	t.Cleanup(func() { w.Close() })
+	l := zap.New(newSinkCore(newTestEncoder(), w, zapcore.DebugLevel))
+	l.Error("payment failed")
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// local0 (16) * 8 + err (3)
+	if !strings.HasPrefix(msg, "<131>1 ") {
+		t.Errorf("message %q does not start with <131>1", msg)
+	}
+	if !strings.Contains(msg, " orders ") {
+		t.Errorf("message %q does not carry the app name", msg)
+	}
+	if !strings.HasSuffix(msg, ` - - {"level":"error","msg":"payment failed"}`) {
+		t.Errorf("message %q does not end with the entry", msg)
+	}
+}
+
+// fluentServer is a Fluent Forward receiver which keeps the records it
+// receives and acknowledges the chunks if ack is set.
+type fluentServer struct {
+	ack bool
+
+	mu      sync.Mutex
+	records []map[string]interface{}
+	tags    []string
+}
+
+func (s *fluentServer) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		v, err := readTestMsgpack(r)
+		if err != nil {
+			return
+		}
+		message, ok := v.([]interface{})
+		if !ok || len(message) < 3 {
+			return
+		}
+		s.mu.Lock()
+		s.tags = append(s.tags, message[0].(string))
+		s.records = append(s.records, message[2].(map[string]interface{}))
+		s.mu.Unlock()
+		if s.ack && len(message) == 4 {
+			chunk := message[3].(map[string]interface{})["chunk"]
+			conn.Write(appendMsgpack(nil, map[string]interface{}{"ack": chunk}))
+		}
+	}
+}
+
+func (s *fluentServer) received() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]interface{}(nil), s.records...)
+}
+
+func TestFluentSinkWaitsForAcks(t *testing.T) {
+	server := &fluentServer{ack: true}
+	addr := listenTCP(t, server.serve)
+
+	w := NewFluentWriter(FluentConfig{Addr: addr, Tag: "orders", RequireAck: true, Timeout: time.Second})
+	t.Cleanup(func() { w.Close() })
+	l := zap.New(newSinkCore(newTestEncoder(), w, zapcore.DebugLevel))
+	l.Info("created", zap.Int("amount", 42))
+	l.Info("paid")
+
+	// Each write returned after its ack, so the records have arrived.
+	records := server.received()
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records[0]["msg"] != "created" || records[0]["amount"] != int64(42) || records[1]["msg"] != "paid" {
+		t.Errorf("records = %v", records)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.tags[0] != "orders" {
+		t.Errorf("tag = %q, want orders", server.tags[0])
+	}
+}
+
+func TestFluentSinkFailsWithoutAck(t *testing.T) {
+	server := &fluentServer{}
+	addr := listenTCP(t, server.serve)
+
+	w := NewFluentWriter(FluentConfig{Addr: addr, Tag: "orders", RequireAck: true, Timeout: 100 * time.Millisecond})
+	t.Cleanup(func() { w.Close() })
+	if err := w.WriteEntry(zapcore.Entry{Time: time.Now()}, []byte(`{"msg":"lost"}`)); err == nil {
+		t.Error("write succeeded without an ack")
+	}
+	// The entry was resent once over a new connection.
+	if got := len(server.received()); got != 2 {
+		t.Errorf("got %d records, want 2", got)
+	}
+}
+
+// blockingWriter is an entryWriter whose writes wait until release is closed.
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w blockingWriter) WriteEntry(zapcore.Entry, []byte) error {
+	<-w.release
+	return nil
+}
+
+func (w blockingWriter) Sync() error  { return nil }
+func (w blockingWriter) Close() error { return nil }
+
+func TestNetworkSinkDoesNotBlockOnStalledReceiver(t *testing.T) {
+	stalled := blockingWriter{release: make(chan struct{})}
+	out := newAsyncEntryWriter(stalled, 2, OverflowDropNewest)
+	l := zap.New(newSinkCore(newTestEncoder(), out, zapcore.DebugLevel))
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			l.Info("entry")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked on a stalled receiver")
+	}
+	if stats := out.Stats(); stats.Dropped == 0 {
+		t.Errorf("stats = %+v, want dropped entries", stats)
+	}
+	close(stalled.release)
+	out.Close()
+}
+
+func TestNetworkSinksFromEnvAreAsync(t *testing.T) {
+	t.Setenv("LOG_TCP_ADDR", "127.0.0.1:1")
+	t.Setenv("LOG_FLUENT_ADDR", "127.0.0.1:1")
+	sinks := networkSinksFromEnv("orders", zapcore.EncoderConfig{})
+	if len(sinks) != 2 {
+		t.Fatalf("got %d sinks, want 2", len(sinks))
+	}
+	for _, sink := range sinks {
+		if _, ok := sink.out.(*AsyncWriter); !ok {
+			t.Errorf("sink writes to %T, want *AsyncWriter", sink.out)
+		}
+		sink.out.Close()
+	}
+}
+
+func TestReadMsgpackStringRejectsLargeLengths(t *testing.T) {
+	for _, header := range [][]byte{
+		{0xdb, 0xff, 0xff, 0xff, 0xff},
+		{0xc6, 0x10, 0x00, 0x00, 0x00},
+		{0xda, 0xff, 0xff},
+	} {
+		r := bufio.NewReader(bytes.NewReader(header))
+		if _, err := readMsgpackString(r); err == nil {
+			t.Errorf("readMsgpackString(% x) succeeded", header)
+		}
+	}
+	r := bufio.NewReader(bytes.NewReader([]byte{0xde, 0xff, 0xff}))
+	if _, err := readMsgpackStringMap(r); err == nil {
+		t.Error("readMsgpackStringMap accepted 65535 entries")
+	}
+
+	ack := appendMsgpack(nil, map[string]interface{}{"ack": "Y2h1bmsgaWQ="})
+	m, err := readMsgpackStringMap(bufio.NewReader(bytes.NewReader(ack)))
+	if err != nil || m["ack"] != "Y2h1bmsgaWQ=" {
+		t.Errorf("readMsgpackStringMap(ack) = %v, %v", m, err)
+	}
+}
+
+// readTestMsgpack decodes the MessagePack values written by appendMsgpack.
+func readTestMsgpack(r *bufio.Reader) (interface{}, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0 || c == 0xd9 || c == 0xda:
+		r.UnreadByte()
+		return readMsgpackString(r)
+	case c&0xf0 == 0x90:
+		return readTestMsgpackArray(r, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return readTestMsgpackMap(r, int(c&0x0f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcb:
+		var bits uint64
+		err := binary.Read(r, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		buf := make([]byte, 1<<(c-0xcc))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, b := range buf {
+			u = u<<8 | uint64(b)
+		}
+		return int64(u), nil
+	case 0xd7:
+		// The EventTime extension: type, seconds and nanoseconds.
+		var ext struct {
+			Type      int8
+			Sec, Nsec uint32
+		}
+		err := binary.Read(r, binary.BigEndian, &ext)
+		return time.Unix(int64(ext.Sec), int64(ext.Nsec)), err
+	case 0xdc:
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		return readTestMsgpackArray(r, int(n))
+	case 0xde:
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		return readTestMsgpackMap(r, int(n))
+	}
+	return nil, fmt.Errorf("unsupported msgpack type 0x%x", c)
+}
+
+func readTestMsgpackArray(r *bufio.Reader, n int) ([]interface{}, error) {
+	a := make([]interface{}, n)
+	for i := range a {
+		v, err := readTestMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func readTestMsgpackMap(r *bufio.Reader, n int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		if m[k], err = readTestMsgpack(r); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func TestTCPSinkHonorsUDPScheme(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	t.Setenv("LOG_TCP_ADDR", "udp://"+conn.LocalAddr().String())
+	sinks := networkSinksFromEnv("orders", zapcore.EncoderConfig{MessageKey: "msg"})
+	if len(sinks) != 1 {
+		t.Fatalf("got %d sinks, want 1", len(sinks))
+	}
+	l := zap.New(sinks[0])
+	l.Info("over udp")
+	sinks[0].out.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != `{"msg":"over udp"}`+"\n" {
+		t.Errorf("datagram = %q", got)
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	for _, tt := range []struct {
+		addr, network, address string
+		wantErr                bool
+	}{
+		{addr: "collector:5170", network: "tcp", address: "collector:5170"},
+		{addr: "tcp://collector:5170", network: "tcp", address: "collector:5170"},
+		{addr: "UDP://collector:5170", network: "udp", address: "collector:5170"},
+		{addr: "unix:///var/run/fluent.sock", wantErr: true},
+		{addr: "http://collector:5170", wantErr: true},
+	} {
+		network, address, err := parseAddr(tt.addr, "tcp", "tcp", "udp")
+		if (err != nil) != tt.wantErr || network != tt.network || address != tt.address {
+			t.Errorf("parseAddr(%q) = %q, %q, %v", tt.addr, network, address, err)
+		}
+	}
+}
+
+func TestNetworkSinksFromEnvRejectUnknownSchemes(t *testing.T) {
+	t.Setenv("LOG_TCP_ADDR", "http://127.0.0.1:1")
+	t.Setenv("LOG_FLUENT_ADDR", "udp://127.0.0.1:1")
+	t.Setenv("LOG_SYSLOG_ADDR", "https://127.0.0.1:1")
+	if sinks := networkSinksFromEnv("orders", zapcore.EncoderConfig{}); len(sinks) != 0 {
+		t.Errorf("got %d sinks for unsupported schemes", len(sinks))
+	}
+}
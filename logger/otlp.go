@@ -0,0 +1,522 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+const instrumentationName = "github.com/vaish1707/golang-logging-instrumentation/logger"
+
+// OTLPConfig configures the export of log records over OTLP.
+type OTLPConfig struct {
+	// Protocol is grpc, the default, http/protobuf or http/json.
+	Protocol string
+	// Endpoint is the host:port of the collector. It defaults to
+	// localhost:4317 with grpc and to localhost:4318 with the HTTP protocols,
+	// like the trace exporter.
+	Endpoint string
+	// URLPath is the path logs are posted to with the HTTP protocols.
+	// Defaults to /v1/logs.
+	URLPath  string
+	Insecure bool
+	Headers  map[string]string
+	// Compression is gzip or empty for none.
+	Compression string
+	// Resource describes the entity producing the logs, e.g. service.name.
+	Resource []attribute.KeyValue
+	// Level defaults to zapcore.DebugLevel.
+	Level zapcore.LevelEnabler
+
+	// MaxQueueSize is the number of records buffered before new ones are
+	// dropped. Defaults to 2048.
+	MaxQueueSize int
+	// MaxExportBatchSize defaults to 512.
+	MaxExportBatchSize int
+	// BatchTimeout is the longest a record waits before it is exported.
+	// Defaults to 1s.
+	BatchTimeout time.Duration
+	// ExportTimeout bounds a single export call. Defaults to 30s.
+	ExportTimeout time.Duration
+}
+
+func (cfg *OTLPConfig) setDefaults() {
+	if cfg.Protocol == "" {
+		cfg.Protocol = "grpc"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "localhost:4317"
+		if strings.HasPrefix(strings.ToLower(cfg.Protocol), "http/") {
+			cfg.Endpoint = "localhost:4318"
+		}
+	}
+	if cfg.Level == nil {
+		cfg.Level = zapcore.DebugLevel
+	}
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = 2048
+	}
+	if cfg.MaxExportBatchSize <= 0 {
+		cfg.MaxExportBatchSize = 512
+	}
+	if cfg.MaxExportBatchSize > cfg.MaxQueueSize {
+		cfg.MaxExportBatchSize = cfg.MaxQueueSize
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = time.Second
+	}
+	if cfg.ExportTimeout <= 0 {
+		cfg.ExportTimeout = 30 * time.Second
+	}
+}
+
+// OTLPCore is a zapcore.Core which converts every entry into an OpenTelemetry
+// LogRecord and exports it through a batching processor.
+type OTLPCore struct {
+	zapcore.LevelEnabler
+	processor *batchProcessor
+	fields    []zapcore.Field
+}
+
+// NewOTLPCore creates the exporter of cfg.Protocol and starts the batching
+// processor. Connections are established lazily, so an unreachable collector
+// does not fail the call.
+func NewOTLPCore(ctx context.Context, cfg OTLPConfig) (*OTLPCore, error) {
+	cfg.setDefaults()
+	var exporter logExporter
+	var err error
+	switch strings.ToLower(cfg.Protocol) {
+	case "grpc":
+		exporter, err = newOTLPExporter(ctx, cfg)
+	case "http/protobuf", "http/json":
+		exporter = newHTTPLogExporter(cfg)
+	default:
+		err = fmt.Errorf("unsupported OTLP protocol %q", cfg.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &OTLPCore{
+		LevelEnabler: cfg.Level,
+		processor:    newBatchProcessor(exporter, cfg),
+	}, nil
+}
+
+func (c *OTLPCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field{}, c.fields...), fields...)
+	return &clone
+}
+
+func (c *OTLPCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *OTLPCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.processor.onEmit(c.record(ent, fields))
+	return nil
+}
+
+// Sync exports the buffered records.
+func (c *OTLPCore) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.processor.cfg.ExportTimeout)
+	defer cancel()
+	return c.processor.forceFlush(ctx)
+}
+
+// Shutdown exports the buffered records and closes the connection to the
+// collector. Records written afterwards are dropped.
+func (c *OTLPCore) Shutdown(ctx context.Context) error {
+	return c.processor.shutdown(ctx)
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (c *OTLPCore) Dropped() uint64 {
+	return atomic.LoadUint64(&c.processor.dropped)
+}
+
+func (c *OTLPCore) record(ent zapcore.Entry, fields []zapcore.Field) *logspb.LogRecord {
+	var sc trace.SpanContext
+	enc := zapcore.NewMapObjectEncoder()
+	for _, list := range [][]zapcore.Field{c.fields, fields} {
+		for _, f := range list {
+			if f.Key == spanContextKey && f.Type == zapcore.SkipType {
+				sc, _ = f.Interface.(trace.SpanContext)
+				continue
+			}
+			f.AddTo(enc)
+		}
+	}
+	if ent.LoggerName != "" {
+		enc.AddString("logger.name", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		enc.AddString("code.filepath", ent.Caller.File)
+		enc.AddInt("code.lineno", ent.Caller.Line)
+		if ent.Caller.Function != "" {
+			enc.AddString("code.function", ent.Caller.Function)
+		}
+	}
+	if ent.Stack != "" {
+		enc.AddString("exception.stacktrace", ent.Stack)
+	}
+
+	record := &logspb.LogRecord{
+		TimeUnixNano:         uint64(ent.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       severityNumber(ent.Level),
+		SeverityText:         ent.Level.CapitalString(),
+		Body:                 stringValue(ent.Message),
+		Attributes:           keyValues(enc.Fields),
+	}
+	if sc.IsValid() {
+		traceID, spanID := sc.TraceID(), sc.SpanID()
+		record.TraceId = traceID[:]
+		record.SpanId = spanID[:]
+		record.Flags = uint32(sc.TraceFlags())
+	}
+	return record
+}
+
+func severityNumber(level zapcore.Level) logspb.SeverityNumber {
+	switch level {
+	case zapcore.DebugLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case zapcore.InfoLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case zapcore.WarnLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case zapcore.ErrorLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case zapcore.DPanicLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	case zapcore.PanicLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL2
+	case zapcore.FatalLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL3
+	}
+	return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+}
+
+func keyValues(m map[string]interface{}) []*commonpb.KeyValue {
+	kvs := make([]*commonpb.KeyValue, 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, &commonpb.KeyValue{Key: k, Value: anyValue(v)})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func intValue(i int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+}
+
+func doubleValue(f float64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+}
+
+// anyValue converts the values produced by zapcore.MapObjectEncoder and
+// attribute.Value.AsInterface into an OTLP AnyValue.
+func anyValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return stringValue(v)
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int:
+		return intValue(int64(v))
+	case int8:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint:
+		return intValue(int64(v))
+	case uint8:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case uint64:
+		if v > math.MaxInt64 {
+			return stringValue(fmt.Sprint(v))
+		}
+		return intValue(int64(v))
+	case uintptr:
+		return intValue(int64(v))
+	case float32:
+		return doubleValue(float64(v))
+	case float64:
+		return doubleValue(v)
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case time.Time:
+		return stringValue(v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return stringValue(v.String())
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i := range v {
+			values[i] = anyValue(v[i])
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case []string:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i := range v {
+			values[i] = stringValue(v[i])
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case []int64:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i := range v {
+			values[i] = intValue(v[i])
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case []float64:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i := range v {
+			values[i] = doubleValue(v[i])
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case []bool:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i := range v {
+			values[i] = anyValue(v[i])
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]interface{}:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: keyValues(v)}}}
+	case fmt.Stringer:
+		return stringValue(v.String())
+	}
+	// Reflected values are exported as their JSON encoding.
+	b, err := json.Marshal(v)
+	if err != nil {
+		return stringValue(fmt.Sprint(v))
+	}
+	return stringValue(string(b))
+}
+
+// logExporter sends batches of log records to the collector.
+type logExporter interface {
+	export(ctx context.Context, records []*logspb.LogRecord) error
+	shutdown() error
+}
+
+// otlpExporter sends batches of log records over OTLP/gRPC.
+type otlpExporter struct {
+	conn     *grpc.ClientConn
+	client   collogspb.LogsServiceClient
+	headers  metadata.MD
+	resource *resourcepb.Resource
+	timeout  time.Duration
+}
+
+func newOTLPExporter(ctx context.Context, cfg OTLPConfig) (*otlpExporter, error) {
+	creds := credentials.NewClientTLSFromCert(nil, "")
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if cfg.Compression == gzip.Name {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+	conn, err := grpc.DialContext(ctx, cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("dial otlp log endpoint error: %w", err)
+	}
+
+	return &otlpExporter{
+		conn:     conn,
+		client:   collogspb.NewLogsServiceClient(conn),
+		headers:  metadata.New(cfg.Headers),
+		resource: otlpResource(cfg.Resource),
+		timeout:  cfg.ExportTimeout,
+	}, nil
+}
+
+func otlpResource(resource []attribute.KeyValue) *resourcepb.Resource {
+	attrs := make(map[string]interface{}, len(resource))
+	for _, kv := range resource {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return &resourcepb.Resource{Attributes: keyValues(attrs)}
+}
+
+// exportRequest wraps records in an export request for resource.
+func exportRequest(resource *resourcepb.Resource, records []*logspb.LogRecord) *collogspb.ExportLogsServiceRequest {
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: instrumentationName},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+func (e *otlpExporter) export(ctx context.Context, records []*logspb.LogRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, e.headers)
+
+	_, err := e.client.Export(ctx, exportRequest(e.resource, records))
+	if err != nil {
+		return fmt.Errorf("export logs error: %w", err)
+	}
+	return nil
+}
+
+func (e *otlpExporter) shutdown() error {
+	return e.conn.Close()
+}
+
+// batchProcessor queues records and exports them in batches from a single
+// goroutine, so a slow collector never blocks the caller.
+type batchProcessor struct {
+	exporter logExporter
+	cfg      OTLPConfig
+	queue    chan *logspb.LogRecord
+	flush    chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	dropped  uint64
+}
+
+func newBatchProcessor(exporter logExporter, cfg OTLPConfig) *batchProcessor {
+	p := &batchProcessor{
+		exporter: exporter,
+		cfg:      cfg,
+		queue:    make(chan *logspb.LogRecord, cfg.MaxQueueSize),
+		flush:    make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *batchProcessor) onEmit(record *logspb.LogRecord) {
+	select {
+	case <-p.stop:
+		atomic.AddUint64(&p.dropped, 1)
+		return
+	default:
+	}
+	select {
+	case p.queue <- record:
+	default:
+		atomic.AddUint64(&p.dropped, 1)
+	}
+}
+
+func (p *batchProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.BatchTimeout)
+	defer ticker.Stop()
+
+	batch := make([]*logspb.LogRecord, 0, p.cfg.MaxExportBatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.export(context.Background(), batch); err != nil {
+			otel.Handle(err)
+		}
+		batch = make([]*logspb.LogRecord, 0, p.cfg.MaxExportBatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case record := <-p.queue:
+				batch = append(batch, record)
+				if len(batch) == p.cfg.MaxExportBatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case record := <-p.queue:
+			batch = append(batch, record)
+			if len(batch) == p.cfg.MaxExportBatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case flushed := <-p.flush:
+			drain()
+			close(flushed)
+		case <-p.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (p *batchProcessor) forceFlush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case p.flush <- flushed:
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *batchProcessor) shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	select {
+	case <-p.done:
+		return p.exporter.shutdown()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
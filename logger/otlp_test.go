@@ -0,0 +1,280 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeLogsReceiver is an OTLP/gRPC logs receiver which keeps the requests it
+// receives.
+type fakeLogsReceiver struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	headers  []metadata.MD
+}
+
+func (r *fakeLogsReceiver) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.headers = append(r.headers, md)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func (r *fakeLogsReceiver) records() []*logspb.LogRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []*logspb.LogRecord
+	for _, req := range r.requests {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+// startFakeLogsReceiver serves a fakeLogsReceiver on a local port until the
+// test ends.
+func startFakeLogsReceiver(t *testing.T) (*fakeLogsReceiver, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := &fakeLogsReceiver{}
+	srv := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(srv, receiver)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return receiver, lis.Addr().String()
+}
+
+func TestOTLPCoreExportsLogRecords(t *testing.T) {
+	receiver, endpoint := startFakeLogsReceiver(t)
+
+	core, err := NewOTLPCore(context.Background(), OTLPConfig{
+		Endpoint: endpoint,
+		Insecure: true,
+		Headers:  map[string]string{"signoz-access-token": "secret"},
+		Resource: []attribute.KeyValue{attribute.String("service.name", "test-service")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { core.Shutdown(context.Background()) })
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	l := zap.New(core).Named("orders").With(zap.String("requestId", "req-1"))
+	l.Warn("payment failed", zap.Int("amount", 42), spanContextField(sc))
+	if err := core.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := receiver.records()
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	record := records[0]
+	if record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_WARN || record.SeverityText != "WARN" {
+		t.Errorf("severity = %v %q, want WARN", record.SeverityNumber, record.SeverityText)
+	}
+	if got := record.Body.GetStringValue(); got != "payment failed" {
+		t.Errorf("body = %q, want %q", got, "payment failed")
+	}
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	if string(record.TraceId) != string(traceID[:]) || string(record.SpanId) != string(spanID[:]) {
+		t.Errorf("trace_id, span_id = %x, %x, want %x, %x", record.TraceId, record.SpanId, traceID[:], spanID[:])
+	}
+	if record.Flags != uint32(trace.FlagsSampled) {
+		t.Errorf("flags = %d, want %d", record.Flags, trace.FlagsSampled)
+	}
+
+	attrs := map[string]interface{}{}
+	for _, kv := range record.Attributes {
+		if v, ok := kv.Value.Value.(*commonpb.AnyValue_StringValue); ok {
+			attrs[kv.Key] = v.StringValue
+		} else {
+			attrs[kv.Key] = kv.Value.GetIntValue()
+		}
+	}
+	want := map[string]interface{}{"requestId": "req-1", "amount": int64(42), "logger.name": "orders"}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("attribute %s = %v, want %v", k, attrs[k], v)
+		}
+	}
+	if _, ok := attrs[spanContextKey]; ok {
+		t.Errorf("the span context was exported as an attribute")
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	resource := receiver.requests[0].ResourceLogs[0].Resource
+	if len(resource.Attributes) != 1 || resource.Attributes[0].Value.GetStringValue() != "test-service" {
+		t.Errorf("resource = %v, want service.name=test-service", resource.Attributes)
+	}
+	if got := receiver.headers[0].Get("signoz-access-token"); len(got) != 1 || got[0] != "secret" {
+		t.Errorf("signoz-access-token header = %v, want secret", got)
+	}
+}
+
+func TestOTLPCoreBatchesRecords(t *testing.T) {
+	receiver, endpoint := startFakeLogsReceiver(t)
+
+	core, err := NewOTLPCore(context.Background(), OTLPConfig{
+		Endpoint:           endpoint,
+		Insecure:           true,
+		Level:              zapcore.InfoLevel,
+		MaxExportBatchSize: 10,
+		BatchTimeout:       time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := zap.New(core)
+	for i := 0; i < 25; i++ {
+		l.Info("entry", zap.Int("i", i))
+	}
+	l.Debug("below the level of the core")
+	if err := core.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(receiver.records()); got != 25 {
+		t.Errorf("got %d records, want 25", got)
+	}
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if got := len(receiver.requests); got != 3 {
+		t.Errorf("got %d export requests, want 3 batches of at most 10", got)
+	}
+}
+
+func TestOTLPCoreExportsOverHTTP(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0xab, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  trace.SpanID{0xcd, 2, 3, 4, 5, 6, 7, 8},
+	})
+	for _, protocol := range []string{"http/protobuf", "http/json"} {
+		t.Run(protocol, func(t *testing.T) {
+			type request struct {
+				path, contentType, token string
+				body                     []byte
+			}
+			requests := make(chan request, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests <- request{r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("signoz-access-token"), body}
+			}))
+			defer srv.Close()
+
+			core, err := NewOTLPCore(context.Background(), OTLPConfig{
+				Protocol: protocol,
+				Endpoint: strings.TrimPrefix(srv.URL, "http://"),
+				URLPath:  "/collector/v1/logs",
+				Insecure: true,
+				Headers:  map[string]string{"signoz-access-token": "secret"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			zap.New(core).Warn("payment failed", spanContextField(sc))
+			if err := core.Shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			req := <-requests
+			if req.path != "/collector/v1/logs" || req.token != "secret" {
+				t.Errorf("path, token = %s, %s, want /collector/v1/logs, secret", req.path, req.token)
+			}
+			if protocol == "http/protobuf" {
+				if req.contentType != "application/x-protobuf" {
+					t.Errorf("content type = %s", req.contentType)
+				}
+				var export collogspb.ExportLogsServiceRequest
+				if err := proto.Unmarshal(req.body, &export); err != nil {
+					t.Fatal(err)
+				}
+				record := export.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+				if record.Body.GetStringValue() != "payment failed" || record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_WARN {
+					t.Errorf("record = %v", record)
+				}
+				return
+			}
+			if req.contentType != "application/json" {
+				t.Errorf("content type = %s", req.contentType)
+			}
+			var export struct {
+				ResourceLogs []struct {
+					ScopeLogs []struct {
+						LogRecords []struct {
+							SeverityNumber int
+							TraceID        string `json:"traceId"`
+							SpanID         string `json:"spanId"`
+							Body           struct{ StringValue string }
+						}
+					}
+				}
+			}
+			if err := json.Unmarshal(req.body, &export); err != nil {
+				t.Fatal(err)
+			}
+			record := export.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+			if record.Body.StringValue != "payment failed" || record.SeverityNumber != 13 {
+				t.Errorf("record = %+v", record)
+			}
+			if record.TraceID != sc.TraceID().String() || record.SpanID != sc.SpanID().String() {
+				t.Errorf("trace_id, span_id = %s, %s, want hex %s, %s", record.TraceID, record.SpanID, sc.TraceID(), sc.SpanID())
+			}
+		})
+	}
+}
+
+func TestNewOTLPCoreRejectsUnknownProtocol(t *testing.T) {
+	if _, err := NewOTLPCore(context.Background(), OTLPConfig{Protocol: "http/xml"}); err == nil {
+		t.Error("created a core for an unknown protocol")
+	}
+}
+
+func TestOTLPConfigDefaultEndpoint(t *testing.T) {
+	for protocol, want := range map[string]string{
+		"":              "localhost:4317",
+		"grpc":          "localhost:4317",
+		"http/protobuf": "localhost:4318",
+		"http/json":     "localhost:4318",
+	} {
+		cfg := OTLPConfig{Protocol: protocol}
+		cfg.setDefaults()
+		if cfg.Endpoint != want {
+			t.Errorf("protocol %q: endpoint = %s, want %s", protocol, cfg.Endpoint, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vaish1707/golang-logging-instrumentation/internal/otlphttp"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// httpLogExporter sends batches of log records with OTLP/HTTP, encoded as
+// protobuf or JSON.
+type httpLogExporter struct {
+	client   *otlphttp.Client
+	json     bool
+	resource *resourcepb.Resource
+}
+
+func newHTTPLogExporter(cfg OTLPConfig) *httpLogExporter {
+	scheme := "https"
+	if cfg.Insecure {
+		scheme = "http"
+	}
+	path := cfg.URLPath
+	if path == "" {
+		path = "/v1/logs"
+	}
+	return &httpLogExporter{
+		client: &otlphttp.Client{
+			URL:     scheme + "://" + cfg.Endpoint + path,
+			Headers: cfg.Headers,
+			Gzip:    cfg.Compression == "gzip",
+			HTTP:    &http.Client{Timeout: cfg.ExportTimeout},
+		},
+		json:     strings.EqualFold(cfg.Protocol, "http/json"),
+		resource: otlpResource(cfg.Resource),
+	}
+}
+
+func (e *httpLogExporter) export(ctx context.Context, records []*logspb.LogRecord) error {
+	req := exportRequest(e.resource, records)
+	contentType := "application/x-protobuf"
+	var body []byte
+	var err error
+	if e.json {
+		contentType = "application/json"
+		body, err = otlphttp.MarshalJSON(req)
+	} else {
+		body, err = proto.Marshal(req)
+	}
+	if err != nil {
+		return fmt.Errorf("encode logs error: %w", err)
+	}
+	if err := e.client.Post(ctx, contentType, body); err != nil {
+		return fmt.Errorf("export logs error: %w", err)
+	}
+	return nil
+}
+
+func (e *httpLogExporter) shutdown() error {
+	e.client.Close()
+	return nil
+}
@@ -0,0 +1,333 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactMode is how a Redactor replaces a sensitive value.
+type RedactMode string
+
+const (
+	// RedactMask replaces the value with a fixed mask.
+	RedactMask RedactMode = "mask"
+	// RedactHash replaces the value with a keyed hash, so equal values can
+	// still be correlated.
+	RedactHash RedactMode = "hash"
+)
+
+// DefaultRedactKeys are the field names redacted unless configured otherwise.
+var DefaultRedactKeys = []string{"account", "amount", "price", "password", "secret", "token", "authorization", "cookie"}
+
+// RedactionConfig configures a Redactor.
+type RedactionConfig struct {
+	// Keys are matched case-insensitively against field and JSON keys.
+	Keys []string
+	// Patterns are matched against field and JSON keys.
+	Patterns []*regexp.Regexp
+	Mode     RedactMode
+	// Mask replaces the values in RedactMask mode. Defaults to "[REDACTED]".
+	Mask string
+	// HashKey keys the hash in RedactHash mode. Without a key the hashes of
+	// small value sets such as amounts could be reversed by brute force, so
+	// the values are masked instead.
+	HashKey string
+}
+
+func redactionConfigFromEnv() (RedactionConfig, bool) {
+	cfg := RedactionConfig{
+		Keys:    DefaultRedactKeys,
+		Mode:    RedactMode(os.Getenv("LOG_REDACT_MODE")),
+		HashKey: os.Getenv("LOG_REDACT_HASH_KEY"),
+	}
+	if keys := os.Getenv("LOG_REDACT_KEYS"); keys != "" {
+		cfg.Keys = strings.Split(keys, ",")
+	}
+	if patterns := os.Getenv("LOG_REDACT_PATTERNS"); patterns != "" {
+		for _, p := range strings.Split(patterns, ",") {
+			re, err := regexp.Compile(strings.TrimSpace(p))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid LOG_REDACT_PATTERNS entry %q: %v\n", p, err)
+				continue
+			}
+			cfg.Patterns = append(cfg.Patterns, re)
+		}
+	}
+	return cfg, envBool("LOG_REDACT", true)
+}
+
+// Redactor masks or hashes the values of sensitive keys in log fields and
+// JSON documents.
+type Redactor struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+	mode     RedactMode
+	mask     string
+	hashKey  []byte
+}
+
+func NewRedactor(cfg RedactionConfig) *Redactor {
+	r := &Redactor{
+		keys:     make(map[string]struct{}, len(cfg.Keys)),
+		patterns: cfg.Patterns,
+		mode:     cfg.Mode,
+		mask:     cfg.Mask,
+		hashKey:  []byte(cfg.HashKey),
+	}
+	for _, k := range cfg.Keys {
+		if k = strings.TrimSpace(k); k != "" {
+			r.keys[strings.ToLower(k)] = struct{}{}
+		}
+	}
+	if r.mode == "" {
+		r.mode = RedactMask
+	}
+	if r.mode == RedactHash && len(r.hashKey) == 0 {
+		fmt.Fprintln(os.Stderr, "redaction: hash mode requires LOG_REDACT_HASH_KEY, masking values instead")
+		r.mode = RedactMask
+	}
+	if r.mask == "" {
+		r.mask = "[REDACTED]"
+	}
+	return r
+}
+
+// Sensitive reports whether the values of key are redacted.
+func (r *Redactor) Sensitive(key string) bool {
+	if r.isKey(key) {
+		return true
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKey reports whether key is one of the configured keys. The keys are
+// lowered once by NewRedactor; mixed-case keys like requestId are lowered
+// into a stack buffer, since the lookup of a converted byte slice does not
+// allocate as strings.ToLower would.
+func (r *Redactor) isKey(key string) bool {
+	upper := false
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c >= utf8.RuneSelf {
+			_, ok := r.keys[strings.ToLower(key)]
+			return ok
+		} else if 'A' <= c && c <= 'Z' {
+			upper = true
+		}
+	}
+	if !upper {
+		_, ok := r.keys[key]
+		return ok
+	}
+	var buf [64]byte
+	if len(key) > len(buf) {
+		_, ok := r.keys[strings.ToLower(key)]
+		return ok
+	}
+	b := buf[:len(key)]
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b[i] = c
+	}
+	_, ok := r.keys[string(b)]
+	return ok
+}
+
+// Value returns the replacement for a sensitive value.
+func (r *Redactor) Value(value string) string {
+	if r.mode != RedactHash {
+		return r.mask
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(value))
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// ScrubJSON redacts the sensitive keys at any depth of a JSON document.
+// Anything which is not JSON is returned unchanged.
+func (r *Redactor) ScrubJSON(body []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return body
+	}
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return body
+	}
+	scrubbed, changed := r.scrub(doc)
+	if !changed {
+		return body
+	}
+	out, err := json.Marshal(scrubbed)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// maybeJSON reports whether s could be a JSON document, so that plain strings
+// are not copied into a byte slice for ScrubJSON.
+func maybeJSON(s string) bool {
+	s = strings.TrimSpace(s)
+	return s != "" && (s[0] == '{' || s[0] == '[')
+}
+
+// scrub redacts the sensitive keys of a decoded JSON or zap map value.
+func (r *Redactor) scrub(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		changed := false
+		for k, child := range v {
+			if r.Sensitive(k) {
+				v[k] = r.Value(stringify(child))
+				changed = true
+				continue
+			}
+			if scrubbed, ok := r.scrub(child); ok {
+				v[k] = scrubbed
+				changed = true
+			}
+		}
+		return v, changed
+	case []interface{}:
+		changed := false
+		for i, child := range v {
+			if scrubbed, ok := r.scrub(child); ok {
+				v[i] = scrubbed
+				changed = true
+			}
+		}
+		return v, changed
+	case string:
+		if !maybeJSON(v) {
+			break
+		}
+		if scrubbed := r.ScrubJSON([]byte(v)); !bytes.Equal(scrubbed, []byte(v)) {
+			return string(scrubbed), true
+		}
+	}
+	return v, false
+}
+
+// Fields returns fields with the sensitive values redacted. The input is not
+// modified.
+func (r *Redactor) Fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		redacted, changed := r.field(f)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *Redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.SkipType, zapcore.NamespaceType:
+		return f, false
+	}
+	if r.Sensitive(f.Key) {
+		return zap.String(f.Key, r.Value(fieldString(f))), true
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		if !maybeJSON(f.String) {
+			break
+		}
+		if scrubbed := r.ScrubJSON([]byte(f.String)); !bytes.Equal(scrubbed, []byte(f.String)) {
+			return zap.String(f.Key, string(scrubbed)), true
+		}
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			if scrubbed := r.ScrubJSON(b); !bytes.Equal(scrubbed, b) {
+				return zap.ByteString(f.Key, scrubbed), true
+			}
+		}
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		value := enc.Fields[f.Key]
+		if f.Type == zapcore.ReflectType {
+			// Reflected values are kept as is by the map encoder.
+			b, err := json.Marshal(value)
+			if err != nil {
+				return f, false
+			}
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+			if err := dec.Decode(&value); err != nil {
+				return f, false
+			}
+		}
+		if scrubbed, changed := r.scrub(value); changed {
+			return zap.Any(f.Key, scrubbed), true
+		}
+	}
+	return f, false
+}
+
+// fieldString returns the encoded value of a single field.
+func fieldString(f zapcore.Field) string {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return stringify(enc.Fields[f.Key])
+}
+
+// RedactionCore redacts the fields of every entry before it reaches the
+// wrapped core.
+type RedactionCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+func NewRedactionCore(core zapcore.Core, redactor *Redactor) *RedactionCore {
+	return &RedactionCore{Core: core, redactor: redactor}
+}
+
+func (c *RedactionCore) With(fields []zapcore.Field) zapcore.Core {
+	return &RedactionCore{Core: c.Core.With(c.redactor.Fields(fields)), redactor: c.redactor}
+}
+
+func (c *RedactionCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *RedactionCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeThrough(c.Core, ent, c.redactor.Fields(fields))
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted returns the fields redacted by r as the JSON encoder would see
+// them.
+func redacted(r *Redactor, fields ...zap.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range r.Fields(fields) {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+func TestRedactorFields(t *testing.T) {
+	r := NewRedactor(RedactionConfig{
+		Keys:     []string{"password", "Token", " amount "},
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)card`)},
+	})
+	for _, tt := range []struct {
+		name  string
+		field zap.Field
+		want  interface{}
+	}{
+		{"key", zap.String("password", "hunter2"), "[REDACTED]"},
+		{"key of another case", zap.String("PassWord", "hunter2"), "[REDACTED]"},
+		{"key configured in another case", zap.String("token", "t"), "[REDACTED]"},
+		{"key with spaces", zap.Int("amount", 42), "[REDACTED]"},
+		{"other key", zap.String("requestId", "req-1"), "req-1"},
+		{"regex key", zap.String("creditCardNumber", "4111"), "[REDACTED]"},
+		{
+			"nested JSON",
+			zap.String("requestBody", `{"user":{"name":"ann","password":"hunter2"}}`),
+			`{"user":{"name":"ann","password":"[REDACTED]"}}`,
+		},
+		{
+			"JSON array",
+			zap.String("requestBody", `[{"token":"t1"},{"id":1}]`),
+			`[{"token":"[REDACTED]"},{"id":1}]`,
+		},
+		{"plain string", zap.String("requestBody", "password=hunter2"), "password=hunter2"},
+		{"invalid JSON", zap.String("requestBody", `{"password":`), `{"password":`},
+		{
+			"reflected value",
+			zap.Any("order", map[string]interface{}{"items": []interface{}{map[string]interface{}{"cardNumber": "4111"}}}),
+			map[string]interface{}{"items": []interface{}{map[string]interface{}{"cardNumber": "[REDACTED]"}}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redacted(r, tt.field)[tt.field.Key]
+			if !equalJSONValue(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// equalJSONValue compares decoded JSON values by their printed form.
+func equalJSONValue(a, b interface{}) bool {
+	return stringify(a) == stringify(b)
+}
+
+func TestRedactorFieldsKeepsInput(t *testing.T) {
+	r := NewRedactor(RedactionConfig{Keys: []string{"password"}})
+	fields := []zap.Field{zap.String("userId", "42"), zap.String("password", "hunter2")}
+	r.Fields(fields)
+	if fields[1].String != "hunter2" {
+		t.Errorf("input field modified to %q", fields[1].String)
+	}
+	clean := []zap.Field{zap.String("userId", "42")}
+	if got := r.Fields(clean); &got[0] != &clean[0] {
+		t.Error("fields without sensitive values were copied")
+	}
+}
+
+func TestRedactorModes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  RedactionConfig
+		want func(string) bool
+	}{
+		{"mask", RedactionConfig{Mode: RedactMask}, func(v string) bool { return v == "[REDACTED]" }},
+		{"custom mask", RedactionConfig{Mode: RedactMask, Mask: "***"}, func(v string) bool { return v == "***" }},
+		{"hash", RedactionConfig{Mode: RedactHash, HashKey: "k"}, func(v string) bool {
+			return strings.HasPrefix(v, "sha256:") && len(v) == len("sha256:")+16
+		}},
+		{"hash without a key", RedactionConfig{Mode: RedactHash}, func(v string) bool { return v == "[REDACTED]" }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Keys = []string{"account"}
+			r := NewRedactor(tt.cfg)
+			got := redacted(r, zap.String("account", "ACC-1"))["account"].(string)
+			if !tt.want(got) {
+				t.Errorf("got %q", got)
+			}
+		})
+	}
+}
+
+func TestRedactorHashCorrelates(t *testing.T) {
+	r := NewRedactor(RedactionConfig{Mode: RedactHash, HashKey: "k"})
+	if r.Value("ACC-1") != r.Value("ACC-1") {
+		t.Error("equal values hash differently")
+	}
+	if r.Value("ACC-1") == r.Value("ACC-2") {
+		t.Error("different values hash the same")
+	}
+	other := NewRedactor(RedactionConfig{Mode: RedactHash, HashKey: "other"})
+	if r.Value("ACC-1") == other.Value("ACC-1") {
+		t.Error("the hash does not depend on the key")
+	}
+}
+
+func TestRedactionConfigFromEnvEmptyHashKey(t *testing.T) {
+	t.Setenv("LOG_REDACT_MODE", "hash")
+	t.Setenv("LOG_REDACT_HASH_KEY", "")
+	t.Setenv("LOG_REDACT_KEYS", "account")
+	cfg, ok := redactionConfigFromEnv()
+	if !ok {
+		t.Fatal("redaction disabled by default")
+	}
+	if got := redacted(NewRedactor(cfg), zap.String("account", "ACC-1"))["account"]; got != "[REDACTED]" {
+		t.Errorf("got %v, want the mask", got)
+	}
+}
+
+func TestRedactorSensitiveDoesNotAllocate(t *testing.T) {
+	r := NewRedactor(RedactionConfig{Keys: DefaultRedactKeys})
+	for _, key := range []string{"requestId", "userId", "Authorization", "message"} {
+		if allocs := testing.AllocsPerRun(100, func() { r.Sensitive(key) }); allocs != 0 {
+			t.Errorf("Sensitive(%q) allocates %v times", key, allocs)
+		}
+	}
+}
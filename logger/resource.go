@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// DefaultResourceKeys are the resource attributes added to every entry of the
+// local and network sinks, so that log lines carry the same identity as the
+// spans of the service.
+var DefaultResourceKeys = []string{
+	"service.name",
+	"service.version",
+	"service.instance.id",
+	"deployment.environment",
+	"host.name",
+}
+
+var (
+	resourceMu    sync.RWMutex
+	resourceAttrs []attribute.KeyValue
+	resourceKeys  = DefaultResourceKeys
+)
+
+// SetResource sets the attributes describing the entity producing the logs,
+// such as service.name, for the encoders which output them. Those listed in
+// LOG_RESOURCE_KEYS are added to every entry.
+func SetResource(attrs []attribute.KeyValue) {
+	resourceMu.Lock()
+	resourceAttrs = attrs
+	resourceMu.Unlock()
+	build()
+}
+
+// resourceKeysFromEnv reads LOG_RESOURCE_KEYS. The dev profile leaves them
+// out by default to keep the console readable.
+func resourceKeysFromEnv() []string {
+	value, ok := os.LookupEnv("LOG_RESOURCE_KEYS")
+	if !ok {
+		if devProfile() {
+			return nil
+		}
+		return DefaultResourceKeys
+	}
+	var keys []string
+	for _, k := range strings.Split(value, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// resourceMap returns the resource attributes keyed by name.
+func resourceMap() map[string]interface{} {
+	resourceMu.RLock()
+	defer resourceMu.RUnlock()
+	m := make(map[string]interface{}, len(resourceAttrs))
+	for _, kv := range resourceAttrs {
+		m[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return m
+}
+
+// resourceFields returns the resource attributes in resourceKeys as fields.
+func resourceFields() []zap.Field {
+	resourceMu.RLock()
+	defer resourceMu.RUnlock()
+	var fields []zap.Field
+	for _, key := range resourceKeys {
+		for _, kv := range resourceAttrs {
+			if string(kv.Key) == key {
+				fields = append(fields, zap.Any(key, kv.Value.AsInterface()))
+				break
+			}
+		}
+	}
+	return fields
+}
@@ -0,0 +1,341 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// backupTimeFormat is the UTC timestamp added to the name of rotated files.
+// Files rotated within the same millisecond get a -1, -2, ... suffix.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateConfig configures a RotatingWriter.
+type RotateConfig struct {
+	Filename string
+	// MaxSizeMB is the size at which the file is rotated. Zero disables
+	// size-based rotation.
+	MaxSizeMB int
+	// MaxAge removes rotated files older than this. Zero keeps them.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept. Zero keeps them all.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// RotatingWriter is a zapcore.WriteSyncer that appends to a file and rotates
+// it by size. Rotated files are compressed and removed in the background.
+type RotatingWriter struct {
+	cfg  RotateConfig
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	mill      chan struct{}
+	signals   chan os.Signal
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRotatingWriter opens cfg.Filename, creating its directory if needed.
+func NewRotatingWriter(cfg RotateConfig) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		cfg:    cfg,
+		mill:   make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	go w.runMill()
+	w.mill <- struct{}{}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.cfg.Filename), 0755); err != nil {
+		return fmt.Errorf("create log directory error: %w", err)
+	}
+	file, err := os.OpenFile(w.cfg.Filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open log file error: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat log file error: %w", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	maxSize := int64(w.cfg.MaxSizeMB) * 1024 * 1024
+	if maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Rotate moves the current file aside and starts a new one.
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+func (w *RotatingWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.cfg.Filename, w.backupName(time.Now())); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate log file error: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// backupName returns an unused name for the file rotated at t.
+func (w *RotatingWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.cfg.Filename)
+	name := strings.TrimSuffix(w.cfg.Filename, ext) + "-" + t.UTC().Format(backupTimeFormat)
+	backup := name + ext
+	for seq := 1; fileExists(backup) || fileExists(backup+".gz"); seq++ {
+		backup = fmt.Sprintf("%s-%d%s", name, seq, ext)
+	}
+	return backup
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// Reopen closes and reopens the file, so an external logrotate can move it
+// away first.
+func (w *RotatingWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// ReopenOnSignal reopens the file whenever one of sigs, SIGHUP by default, is
+// received.
+func (w *RotatingWriter) ReopenOnSignal(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	w.signals = make(chan os.Signal, 1)
+	signal.Notify(w.signals, sigs...)
+	go func() {
+		for {
+			select {
+			case <-w.signals:
+				if err := w.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "reopen log file error: %v\n", err)
+				}
+			case <-w.closed:
+				return
+			}
+		}
+	}()
+}
+
+// Close closes the file and stops the background goroutines.
+func (w *RotatingWriter) Close() error {
+	w.closeOnce.Do(func() {
+		if w.signals != nil {
+			signal.Stop(w.signals)
+		}
+		close(w.closed)
+	})
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
+
+func (w *RotatingWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotatingWriter) runMill() {
+	for {
+		select {
+		case <-w.mill:
+			if err := w.millBackups(); err != nil {
+				fmt.Fprintf(os.Stderr, "clean up rotated log files error: %v\n", err)
+			}
+		case <-w.closed:
+			return
+		}
+	}
+}
+
+type backupFile struct {
+	path      string
+	timestamp time.Time
+	seq       int
+}
+
+// millBackups compresses the rotated files and removes those beyond
+// MaxBackups or older than MaxAge.
+func (w *RotatingWriter) millBackups() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove []backupFile
+	if w.cfg.MaxBackups > 0 && len(backups) > w.cfg.MaxBackups {
+		remove = append(remove, backups[w.cfg.MaxBackups:]...)
+		backups = backups[:w.cfg.MaxBackups]
+	}
+	if w.cfg.MaxAge > 0 {
+		cutoff := time.Now().Add(-w.cfg.MaxAge)
+		kept := backups[:0]
+		for _, b := range backups {
+			if b.timestamp.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				kept = append(kept, b)
+			}
+		}
+		backups = kept
+	}
+
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove rotated log file error: %w", err)
+		}
+	}
+	if !w.cfg.Compress {
+		return nil
+	}
+	for _, b := range backups {
+		if strings.HasSuffix(b.path, ".gz") {
+			continue
+		}
+		if err := compressFile(b.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backups lists the rotated files of the writer, newest first.
+func (w *RotatingWriter) backups() ([]backupFile, error) {
+	dir := filepath.Dir(w.cfg.Filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read log directory error: %w", err)
+	}
+
+	ext := filepath.Ext(w.cfg.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(w.cfg.Filename), ext) + "-"
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext), prefix)
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+		timestamp, err := time.ParseInLocation(backupTimeFormat, stamp[:len(backupTimeFormat)], time.UTC)
+		if err != nil {
+			continue
+		}
+		var seq int
+		if suffix := stamp[len(backupTimeFormat):]; suffix != "" {
+			if seq, err = strconv.Atoi(strings.TrimPrefix(suffix, "-")); err != nil || suffix[0] != '-' || seq < 1 {
+				continue
+			}
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), timestamp: timestamp, seq: seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].timestamp.Equal(backups[j].timestamp) {
+			return backups[i].timestamp.After(backups[j].timestamp)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+	return backups, nil
+}
+
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open rotated log file error: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("create compressed log file error: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return fmt.Errorf("compress log file error: %w", err)
+	}
+	if err = gz.Close(); err != nil {
+		return fmt.Errorf("compress log file error: %w", err)
+	}
+	if err = dst.Close(); err != nil {
+		return fmt.Errorf("compress log file error: %w", err)
+	}
+	return os.Remove(path)
+}
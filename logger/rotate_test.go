@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newTestRotatingWriter(t *testing.T, cfg RotateConfig) *RotatingWriter {
+	t.Helper()
+	if cfg.Filename == "" {
+		cfg.Filename = filepath.Join(t.TempDir(), "app.log")
+	}
+	w, err := NewRotatingWriter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+// waitFor polls cond until it holds, since the rotated files are compressed
+// and removed in the background.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func backupNames(t *testing.T, w *RotatingWriter) []string {
+	t.Helper()
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(backups))
+	for i, b := range backups {
+		names[i] = filepath.Base(b.path)
+	}
+	return names
+}
+
+func TestRotatingWriterRotatesBySize(t *testing.T) {
+	w := newTestRotatingWriter(t, RotateConfig{MaxSizeMB: 1})
+	line := bytes.Repeat([]byte("x"), 600*1024)
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := len(backupNames(t, w)); got != 2 {
+		t.Errorf("got %d rotated files, want 2", got)
+	}
+	info, err := os.Stat(w.cfg.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(line)) {
+		t.Errorf("current file has %d bytes, want %d", info.Size(), len(line))
+	}
+}
+
+func TestRotatingWriterNamesBackups(t *testing.T) {
+	w := newTestRotatingWriter(t, RotateConfig{})
+	// Names are in UTC whatever the zone, so that they age correctly.
+	at := time.Date(2026, 10, 17, 3, 0, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+	var names []string
+	for i := 0; i < 3; i++ {
+		name := w.backupName(at)
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, filepath.Base(name))
+	}
+	want := []string{"app-2026-10-17T08-00-00.000.log", "app-2026-10-17T08-00-00.000-1.log", "app-2026-10-17T08-00-00.000-2.log"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	backups, err := w.backups()
+	if err != nil || !backups[0].timestamp.Equal(at) {
+		t.Errorf("backups = %v, %v, want timestamp %s", backups, err, at)
+	}
+	// Newest first.
+	if got := backupNames(t, w); strings.Join(got, " ") != strings.Join([]string{want[2], want[1], want[0]}, " ") {
+		t.Errorf("backups = %v", got)
+	}
+}
+
+func TestRotatingWriterPrunesByCount(t *testing.T) {
+	w := newTestRotatingWriter(t, RotateConfig{MaxBackups: 2})
+	for i := 0; i < 5; i++ {
+		w.Write([]byte("entry\n"))
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "two backups", func() bool { return len(backupNames(t, w)) == 2 })
+}
+
+func TestRotatingWriterPrunesByAge(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	old := "app-" + time.Now().Add(-3*time.Hour).UTC().Format(backupTimeFormat) + ".log"
+	if err := os.WriteFile(filepath.Join(filepath.Dir(filename), old), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := newTestRotatingWriter(t, RotateConfig{Filename: filename, MaxAge: time.Hour})
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the old backup to be removed", func() bool {
+		names := backupNames(t, w)
+		return len(names) == 1 && names[0] != old
+	})
+}
+
+func TestRotatingWriterCompressesBackups(t *testing.T) {
+	w := newTestRotatingWriter(t, RotateConfig{Compress: true})
+	w.Write([]byte("rotated entry\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "a compressed backup", func() bool {
+		names := backupNames(t, w)
+		return len(names) == 1 && strings.HasSuffix(names[0], ".log.gz")
+	})
+	f, err := os.Open(filepath.Join(filepath.Dir(w.cfg.Filename), backupNames(t, w)[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(gz)
+	if err != nil || string(b) != "rotated entry\n" {
+		t.Errorf("backup = %q, %v", b, err)
+	}
+}
+
+func TestRotatingWriterReopensOnSIGHUP(t *testing.T) {
+	w := newTestRotatingWriter(t, RotateConfig{})
+	w.ReopenOnSignal()
+	w.Write([]byte("before\n"))
+
+	// logrotate moves the file away, then signals the service.
+	moved := w.cfg.Filename + ".1"
+	if err := os.Rename(w.cfg.Filename, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the file to be reopened", func() bool { return fileExists(w.cfg.Filename) })
+	w.Write([]byte("after\n"))
+
+	for path, want := range map[string]string{moved: "before\n", w.cfg.Filename: "after\n"} {
+		if b, err := os.ReadFile(path); err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v, want %q", filepath.Base(path), b, err, want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	messageCounters = 4096
+	numLevels       = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+)
+
+// SamplingPolicy decides which entries a SamplingCore writes.
+type SamplingPolicy struct {
+	// KeepLevel is the lowest level which is always written.
+	KeepLevel zapcore.Level
+	// UnsampledMinLevel is the lowest level written for traces which were not
+	// sampled. Entries without a trace are not affected.
+	UnsampledMinLevel zapcore.Level
+	// First entries with the same level and message are written every Tick,
+	// then only every Thereafter-th one. First of zero disables this.
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+// DefaultSamplingPolicy keeps warnings and errors, drops debug and info
+// entries of unsampled traces and throttles repeated messages.
+func DefaultSamplingPolicy() SamplingPolicy {
+	return SamplingPolicy{
+		KeepLevel:         zapcore.WarnLevel,
+		UnsampledMinLevel: zapcore.WarnLevel,
+		Tick:              time.Second,
+		First:             100,
+		Thereafter:        100,
+	}
+}
+
+func samplingPolicyFromEnv() (SamplingPolicy, bool) {
+	policy := DefaultSamplingPolicy()
+	if level, ok := levelFromEnv("LOG_SAMPLING_KEEP_LEVEL"); ok {
+		policy.KeepLevel = level
+	}
+	if level, ok := levelFromEnv("LOG_SAMPLING_UNSAMPLED_LEVEL"); ok {
+		policy.UnsampledMinLevel = level
+	}
+	policy.Tick = envDuration("LOG_SAMPLING_TICK", policy.Tick)
+	policy.First = envInt("LOG_SAMPLING_FIRST", policy.First)
+	policy.Thereafter = envInt("LOG_SAMPLING_THEREAFTER", policy.Thereafter)
+	return policy, envBool("LOG_SAMPLING", false)
+}
+
+// SamplingStats counts the entries dropped by a SamplingCore per level.
+type SamplingStats struct {
+	// Unsampled were dropped because their trace was not sampled.
+	Unsampled map[string]uint64 `json:"unsampled"`
+	// Throttled were dropped by the per-message sampling.
+	Throttled map[string]uint64 `json:"throttled"`
+}
+
+type samplingCounters struct {
+	unsampled [numLevels]uint64
+	throttled [numLevels]uint64
+	messages  [numLevels][messageCounters]messageCounter
+}
+
+type messageCounter struct {
+	resetAt int64
+	count   uint64
+}
+
+// inc counts an entry and returns its position in the current tick.
+func (c *messageCounter) inc(now time.Time, tick time.Duration) uint64 {
+	tn := now.UnixNano()
+	resetAfter := atomic.LoadInt64(&c.resetAt)
+	if resetAfter > tn {
+		return atomic.AddUint64(&c.count, 1)
+	}
+
+	atomic.StoreUint64(&c.count, 1)
+	newResetAfter := tn + tick.Nanoseconds()
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAfter, newResetAfter) {
+		// Another goroutine reset the counter first.
+		return atomic.AddUint64(&c.count, 1)
+	}
+	return 1
+}
+
+// SamplingCore drops entries according to a SamplingPolicy. Unlike
+// zapcore.NewSamplerWithOptions it follows the sampling decision of the trace
+// the entry belongs to, so the decision is taken in Write.
+type SamplingCore struct {
+	zapcore.Core
+	policy   SamplingPolicy
+	counters *samplingCounters
+	// spanContext is the span context added through With, if any.
+	spanContext trace.SpanContext
+}
+
+func NewSamplingCore(core zapcore.Core, policy SamplingPolicy) *SamplingCore {
+	return &SamplingCore{
+		Core:     core,
+		policy:   policy,
+		counters: &samplingCounters{},
+	}
+}
+
+func (c *SamplingCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	if sc, ok := spanContextFromFields(fields); ok {
+		clone.spanContext = sc
+	}
+	return &clone
+}
+
+func (c *SamplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *SamplingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.sample(ent, fields) {
+		return writeThrough(c.Core, ent, fields)
+	}
+	return nil
+}
+
+func (c *SamplingCore) sample(ent zapcore.Entry, fields []zapcore.Field) bool {
+	if ent.Level >= c.policy.KeepLevel || ent.Level < zapcore.DebugLevel || ent.Level > zapcore.FatalLevel {
+		return true
+	}
+	i := int(ent.Level - zapcore.DebugLevel)
+
+	sc, ok := spanContextFromFields(fields)
+	if !ok {
+		sc = c.spanContext
+	}
+	if sc.IsValid() && !sc.IsSampled() && ent.Level < c.policy.UnsampledMinLevel {
+		atomic.AddUint64(&c.counters.unsampled[i], 1)
+		return false
+	}
+
+	if c.policy.First <= 0 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(ent.Message))
+	counter := &c.counters.messages[i][h.Sum32()%messageCounters]
+	n := counter.inc(ent.Time, c.policy.Tick)
+	if n <= uint64(c.policy.First) ||
+		(c.policy.Thereafter > 0 && (n-uint64(c.policy.First))%uint64(c.policy.Thereafter) == 0) {
+		return true
+	}
+	atomic.AddUint64(&c.counters.throttled[i], 1)
+	return false
+}
+
+// Stats returns the number of dropped entries so far.
+func (c *SamplingCore) Stats() SamplingStats {
+	stats := SamplingStats{Unsampled: map[string]uint64{}, Throttled: map[string]uint64{}}
+	for i := 0; i < numLevels; i++ {
+		level := (zapcore.DebugLevel + zapcore.Level(i)).String()
+		stats.Unsampled[level] = atomic.LoadUint64(&c.counters.unsampled[i])
+		stats.Throttled[level] = atomic.LoadUint64(&c.counters.throttled[i])
+	}
+	return stats
+}
+
+// SamplingHandler serves the drop counters of the package logger's sampling
+// core.
+func SamplingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sampler == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "log sampling is disabled"})
+			return
+		}
+		writeJSON(w, http.StatusOK, sampler.Stats())
+	})
+}
+
+// spanContextFromFields returns the span context added by LoggerWithCtx.
+func spanContextFromFields(fields []zapcore.Field) (trace.SpanContext, bool) {
+	for i := range fields {
+		if fields[i].Key == spanContextKey && fields[i].Type == zapcore.SkipType {
+			sc, ok := fields[i].Interface.(trace.SpanContext)
+			return sc, ok
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+// writeThrough writes an entry to core as if it had been logged directly, so
+// the levels of the cores behind it still apply. It is used by wrapping cores
+// which can only decide what to write once they see the fields.
+func writeThrough(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) error {
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
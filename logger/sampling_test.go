@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestSamplingLogger(policy SamplingPolicy) (*zap.Logger, *SamplingCore, *observer.ObservedLogs) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	core := NewSamplingCore(obs, policy)
+	return zap.New(core), core, logs
+}
+
+func TestSamplingDropsUnsampledTraces(t *testing.T) {
+	policy := DefaultSamplingPolicy()
+	policy.First = 0
+	l, core, logs := newTestSamplingLogger(policy)
+	sampled := spanContextField(trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled,
+	}))
+	unsampled := spanContextField(trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{2}, SpanID: trace.SpanID{2},
+	}))
+
+	l.Info("sampled", sampled)
+	l.Info("without a trace")
+	l.Debug("unsampled", unsampled)
+	l.Info("unsampled", unsampled)
+	l.Warn("unsampled warning", unsampled)
+	// The span context added through With applies to every entry.
+	l.With(unsampled).Info("unsampled")
+
+	var messages []string
+	for _, e := range logs.All() {
+		messages = append(messages, e.Message)
+	}
+	if want := []string{"sampled", "without a trace", "unsampled warning"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("messages = %q, want %q", messages, want)
+	}
+	stats := core.Stats()
+	if stats.Unsampled["debug"] != 1 || stats.Unsampled["info"] != 2 || stats.Unsampled["warn"] != 0 {
+		t.Errorf("unsampled = %v", stats.Unsampled)
+	}
+}
+
+func TestSamplingFirstThenThereafter(t *testing.T) {
+	policy := DefaultSamplingPolicy()
+	policy.Tick, policy.First, policy.Thereafter = time.Hour, 2, 3
+	l, core, logs := newTestSamplingLogger(policy)
+	for i := 0; i < 10; i++ {
+		l.Info("cache miss", zap.Int("i", i))
+		l.Warn("slow query", zap.Int("i", i))
+	}
+	l.Info("cache hit")
+
+	var kept []int64
+	for _, e := range logs.FilterMessage("cache miss").All() {
+		kept = append(kept, e.ContextMap()["i"].(int64))
+	}
+	// The first 2, then every 3rd: the 5th and the 8th.
+	if want := []int64{0, 1, 4, 7}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept entries %v, want %v", kept, want)
+	}
+	if n := logs.FilterMessage("slow query").Len(); n != 10 {
+		t.Errorf("kept %d warnings, want all 10", n)
+	}
+	if n := logs.FilterMessage("cache hit").Len(); n != 1 {
+		t.Errorf("dropped another message")
+	}
+	if got := core.Stats().Throttled["info"]; got != 6 {
+		t.Errorf("throttled %d info entries, want 6", got)
+	}
+}
+
+func TestMessageCounterResetsEveryTick(t *testing.T) {
+	var c messageCounter
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	for i := uint64(1); i <= 3; i++ {
+		if n := c.inc(now, time.Second); n != i {
+			t.Fatalf("count %d, want %d", n, i)
+		}
+	}
+	if n := c.inc(now.Add(999*time.Millisecond), time.Second); n != 4 {
+		t.Errorf("count %d inside the tick, want 4", n)
+	}
+	if n := c.inc(now.Add(time.Second), time.Second); n != 1 {
+		t.Errorf("count %d after the tick, want 1", n)
+	}
+}
+
+func TestSamplingHandler(t *testing.T) {
+	useDiscardSink(t, zapcore.DebugLevel)
+	saved := sampler
+	t.Cleanup(func() {
+		sampler = saved
+		build()
+	})
+
+	sampler = nil
+	rec := httptest.NewRecorder()
+	SamplingHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log/sampling", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status %d with sampling disabled, want 404", rec.Code)
+	}
+
+	policy := DefaultSamplingPolicy()
+	sampler = NewSamplingCore(nil, policy)
+	build()
+	Ctx(trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1},
+	}))).Info("unsampled")
+
+	rec = httptest.NewRecorder()
+	SamplingHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log/sampling", nil))
+	var stats SamplingStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || stats.Unsampled["info"] != 1 || stats.Throttled["info"] != 0 {
+		t.Errorf("status %d, stats %+v", rec.Code, stats)
+	}
+}
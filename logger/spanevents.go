@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SpanEventConfig controls how LoggerWithCtx mirrors log calls on the span
+// found in its context.
+type SpanEventConfig struct {
+	Enabled bool
+	// MinLevel is the lowest level recorded as a span event.
+	MinLevel zapcore.Level
+	// ErrorLevel is the lowest level which also records an error on the span
+	// and sets its status to Error.
+	ErrorLevel zapcore.Level
+}
+
+var spanEvents = SpanEventConfig{
+	Enabled:    true,
+	MinLevel:   zapcore.InfoLevel,
+	ErrorLevel: zapcore.ErrorLevel,
+}
+
+// SetSpanEvents replaces the span event configuration.
+func SetSpanEvents(cfg SpanEventConfig) {
+	spanEvents = cfg
+}
+
+// spanEventsFromEnv reads LOG_SPAN_EVENTS, LOG_SPAN_EVENTS_LEVEL and
+// LOG_SPAN_ERROR_LEVEL on top of the current configuration.
+func spanEventsFromEnv() SpanEventConfig {
+	cfg := spanEvents
+	cfg.Enabled = envBool("LOG_SPAN_EVENTS", cfg.Enabled)
+	if v, ok := levelFromEnv("LOG_SPAN_EVENTS_LEVEL"); ok {
+		cfg.MinLevel = v
+	}
+	if v, ok := levelFromEnv("LOG_SPAN_ERROR_LEVEL"); ok {
+		cfg.ErrorLevel = v
+	}
+	return cfg
+}
+
+// recordSpanEvent adds msg and the logger and call fields as an event on the
+// recording span and, from ErrorLevel on, records the error and marks the span
+// as failed.
+func recordSpanEvent(span trace.Span, level zapcore.Level, msg string, loggerFields, fields []zap.Field) {
+	cfg := spanEvents
+	if !cfg.Enabled || level < cfg.MinLevel || !span.IsRecording() {
+		return
+	}
+	if len(loggerFields) > 0 {
+		fields = append(append([]zap.Field{}, loggerFields...), fields...)
+	}
+
+	if redactor != nil {
+		fields = redactor.Fields(fields)
+	}
+	attrs := append(attributesFromFields(fields), attribute.String("log.severity", level.CapitalString()))
+	span.AddEvent(msg, trace.WithAttributes(attrs...))
+
+	if level >= cfg.ErrorLevel {
+		err, d, ok := errorFromFields(fields)
+		if !ok {
+			err = errors.New(msg)
+			d = describeError(err)
+		}
+		recordException(span, err, d, msg)
+	}
+}
+
+// attributesFromFields converts zap fields to span attributes. Arrays and
+// objects are stored as their JSON encoding.
+func attributesFromFields(fields []zap.Field) []attribute.KeyValue {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(enc.Fields))
+	for k, v := range enc.Fields {
+		attrs = append(attrs, attributeValue(k, v))
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
+
+func attributeValue(key string, v interface{}) attribute.KeyValue {
+	switch v := v.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int64(key, int64(v))
+	case int16:
+		return attribute.Int64(key, int64(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint8:
+		return attribute.Int64(key, int64(v))
+	case uint16:
+		return attribute.Int64(key, int64(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case fmt.Stringer:
+		return attribute.Stringer(key, v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return attribute.String(key, fmt.Sprint(v))
+	}
+	return attribute.String(key, string(b))
+}
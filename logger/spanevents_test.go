@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// useSpanEvents sets the span event configuration until the test ends.
+func useSpanEvents(t *testing.T, cfg SpanEventConfig) {
+	t.Helper()
+	saved := spanEvents
+	SetSpanEvents(cfg)
+	t.Cleanup(func() { SetSpanEvents(saved) })
+}
+
+func TestSpanEventLevels(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		cfg    SpanEventConfig
+		events []string
+		failed bool
+	}{
+		{
+			"defaults",
+			SpanEventConfig{Enabled: true, MinLevel: zapcore.InfoLevel, ErrorLevel: zapcore.ErrorLevel},
+			[]string{"info", "warn", "error", "exception"},
+			true,
+		},
+		{
+			"debug and warn as errors",
+			SpanEventConfig{Enabled: true, MinLevel: zapcore.DebugLevel, ErrorLevel: zapcore.WarnLevel},
+			[]string{"debug", "info", "warn", "exception", "error", "exception"},
+			true,
+		},
+		{
+			"errors only as events",
+			SpanEventConfig{Enabled: true, MinLevel: zapcore.ErrorLevel, ErrorLevel: zapcore.DPanicLevel},
+			[]string{"error"},
+			false,
+		},
+		{
+			"disabled",
+			SpanEventConfig{Enabled: false, MinLevel: zapcore.DebugLevel, ErrorLevel: zapcore.ErrorLevel},
+			nil,
+			false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			useDiscardSink(t, zapcore.DebugLevel)
+			useSpanEvents(t, tt.cfg)
+			span := recordSpan(t, func(ctx context.Context) {
+				l := Ctx(ctx)
+				l.Debug("debug")
+				l.Info("info")
+				l.Warn("warn")
+				l.Error("error")
+			})
+
+			var names []string
+			for _, ev := range span.Events() {
+				names = append(names, ev.Name)
+			}
+			if !reflect.DeepEqual(names, tt.events) {
+				t.Errorf("events = %q, want %q", names, tt.events)
+			}
+			if failed := span.Status().Code == codes.Error; failed != tt.failed {
+				t.Errorf("status = %+v", span.Status())
+			}
+		})
+	}
+}
+
+func TestSpanEventAttributes(t *testing.T) {
+	useDiscardSink(t, zapcore.DebugLevel)
+	withSpanEvents(t, true)
+	span := recordSpan(t, func(ctx context.Context) {
+		Ctx(ctx).With(zap.String("requestId", "req-1")).
+			Warn("retrying payment", zap.Int("attempt", 2), zap.Bool("idempotent", true), zap.Strings("ids", []string{"o-1"}))
+	})
+
+	ev := span.Events()[0]
+	want := []attribute.KeyValue{
+		attribute.Int("attempt", 2),
+		attribute.Bool("idempotent", true),
+		attribute.String("ids", `["o-1"]`),
+		attribute.String("requestId", "req-1"),
+		attribute.String("log.severity", "WARN"),
+	}
+	if ev.Name != "retrying payment" || !reflect.DeepEqual(ev.Attributes, want) {
+		t.Errorf("event %s %v, want %v", ev.Name, ev.Attributes, want)
+	}
+}
+
+func TestSpanEventErrorWithoutError(t *testing.T) {
+	useDiscardSink(t, zapcore.DebugLevel)
+	withSpanEvents(t, true)
+	span := recordSpan(t, func(ctx context.Context) {
+		Ctx(ctx).Error("order not found")
+	})
+
+	attrs := exceptionEvent(t, span)
+	if got := attrs["exception.message"].AsString(); got != "order not found" {
+		t.Errorf("exception.message = %s", got)
+	}
+	if span.Status().Description != "order not found" {
+		t.Errorf("status = %+v", span.Status())
+	}
+}
+
+func TestSpanEventsFromEnv(t *testing.T) {
+	useSpanEvents(t, SpanEventConfig{Enabled: true, MinLevel: zapcore.InfoLevel, ErrorLevel: zapcore.ErrorLevel})
+	t.Setenv("LOG_SPAN_EVENTS", "false")
+	t.Setenv("LOG_SPAN_EVENTS_LEVEL", "warn")
+	t.Setenv("LOG_SPAN_ERROR_LEVEL", "dpanic")
+
+	want := SpanEventConfig{Enabled: false, MinLevel: zapcore.WarnLevel, ErrorLevel: zapcore.DPanicLevel}
+	if got := spanEventsFromEnv(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,189 @@
+package logger
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SugaredLoggerWithCtx is the loosely typed counterpart of LoggerWithCtx, see
+// zap.SugaredLogger. Its entries carry the same trace and baggage fields.
+type SugaredLoggerWithCtx struct {
+	base LoggerWithCtx
+}
+
+// Sugar wraps the logger in a SugaredLoggerWithCtx.
+func (l LoggerWithCtx) Sugar() SugaredLoggerWithCtx {
+	return SugaredLoggerWithCtx{base: l}
+}
+
+// Desugar returns the strongly typed logger.
+func (s SugaredLoggerWithCtx) Desugar() LoggerWithCtx {
+	return s.base
+}
+
+// With adds the loosely typed key-value pairs to every entry.
+func (s SugaredLoggerWithCtx) With(keysAndValues ...interface{}) SugaredLoggerWithCtx {
+	return SugaredLoggerWithCtx{base: s.base.With(sweetenFields(keysAndValues)...)}
+}
+
+// Named returns a named child logger, see zap.Logger.Named.
+func (s SugaredLoggerWithCtx) Named(name string) SugaredLoggerWithCtx {
+	return SugaredLoggerWithCtx{base: s.base.Named(name)}
+}
+
+func (s SugaredLoggerWithCtx) Debug(args ...interface{}) {
+	if s.base.enabled(zapcore.DebugLevel) {
+		s.base.log(zapcore.DebugLevel, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Info(args ...interface{}) {
+	if s.base.enabled(zapcore.InfoLevel) {
+		s.base.log(zapcore.InfoLevel, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Warn(args ...interface{}) {
+	if s.base.enabled(zapcore.WarnLevel) {
+		s.base.log(zapcore.WarnLevel, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Error(args ...interface{}) {
+	if s.base.enabled(zapcore.ErrorLevel) {
+		s.base.log(zapcore.ErrorLevel, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) DPanic(args ...interface{}) {
+	if s.base.enabled(zapcore.DPanicLevel) {
+		s.base.log(zapcore.DPanicLevel, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Panic(args ...interface{}) {
+	if s.base.enabled(zapcore.PanicLevel) {
+		s.base.log(zapcore.PanicLevel, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Fatal(args ...interface{}) {
+	if s.base.enabled(zapcore.FatalLevel) {
+		s.base.log(zapcore.FatalLevel, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Debugf(template string, args ...interface{}) {
+	if s.base.enabled(zapcore.DebugLevel) {
+		s.base.log(zapcore.DebugLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Infof(template string, args ...interface{}) {
+	if s.base.enabled(zapcore.InfoLevel) {
+		s.base.log(zapcore.InfoLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Warnf(template string, args ...interface{}) {
+	if s.base.enabled(zapcore.WarnLevel) {
+		s.base.log(zapcore.WarnLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Errorf(template string, args ...interface{}) {
+	if s.base.enabled(zapcore.ErrorLevel) {
+		s.base.log(zapcore.ErrorLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) DPanicf(template string, args ...interface{}) {
+	if s.base.enabled(zapcore.DPanicLevel) {
+		s.base.log(zapcore.DPanicLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Panicf(template string, args ...interface{}) {
+	if s.base.enabled(zapcore.PanicLevel) {
+		s.base.log(zapcore.PanicLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Fatalf(template string, args ...interface{}) {
+	if s.base.enabled(zapcore.FatalLevel) {
+		s.base.log(zapcore.FatalLevel, fmt.Sprintf(template, args...), nil)
+	}
+}
+
+func (s SugaredLoggerWithCtx) Debugw(msg string, keysAndValues ...interface{}) {
+	if s.base.enabled(zapcore.DebugLevel) {
+		s.base.log(zapcore.DebugLevel, msg, sweetenFields(keysAndValues))
+	}
+}
+
+func (s SugaredLoggerWithCtx) Infow(msg string, keysAndValues ...interface{}) {
+	if s.base.enabled(zapcore.InfoLevel) {
+		s.base.log(zapcore.InfoLevel, msg, sweetenFields(keysAndValues))
+	}
+}
+
+func (s SugaredLoggerWithCtx) Warnw(msg string, keysAndValues ...interface{}) {
+	if s.base.enabled(zapcore.WarnLevel) {
+		s.base.log(zapcore.WarnLevel, msg, sweetenFields(keysAndValues))
+	}
+}
+
+func (s SugaredLoggerWithCtx) Errorw(msg string, keysAndValues ...interface{}) {
+	if s.base.enabled(zapcore.ErrorLevel) {
+		s.base.log(zapcore.ErrorLevel, msg, sweetenFields(keysAndValues))
+	}
+}
+
+func (s SugaredLoggerWithCtx) DPanicw(msg string, keysAndValues ...interface{}) {
+	if s.base.enabled(zapcore.DPanicLevel) {
+		s.base.log(zapcore.DPanicLevel, msg, sweetenFields(keysAndValues))
+	}
+}
+
+func (s SugaredLoggerWithCtx) Panicw(msg string, keysAndValues ...interface{}) {
+	if s.base.enabled(zapcore.PanicLevel) {
+		s.base.log(zapcore.PanicLevel, msg, sweetenFields(keysAndValues))
+	}
+}
+
+func (s SugaredLoggerWithCtx) Fatalw(msg string, keysAndValues ...interface{}) {
+	if s.base.enabled(zapcore.FatalLevel) {
+		s.base.log(zapcore.FatalLevel, msg, sweetenFields(keysAndValues))
+	}
+}
+
+// sweetenFields converts loosely typed key-value pairs into fields the way
+// zap.SugaredLogger does. zap.Field values are used as is, and values without
+// a string key are logged under "ignored".
+func sweetenFields(args []interface{}) []zap.Field {
+	if len(args) == 0 {
+		return nil
+	}
+	fields := make([]zap.Field, 0, len(args))
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(zap.Field); ok {
+			fields = append(fields, f)
+			i++
+			continue
+		}
+		if i == len(args)-1 {
+			fields = append(fields, zap.Any("ignored", args[i]))
+			break
+		}
+		key, ok := args[i].(string)
+		if !ok {
+			fields = append(fields, zap.Any("ignored", []interface{}{args[i], args[i+1]}))
+		} else {
+			fields = append(fields, zap.Any(key, args[i+1]))
+		}
+		i += 2
+	}
+	return fields
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestSugarKeepsCorrelationFields(t *testing.T) {
+	logs := useObservedSink(t)
+	useCorrelation(t, otelCorrelation)
+	ctx := trace.ContextWithSpanContext(context.Background(), testSpanContext(t))
+	s := Ctx(ctx).With(zap.String("requestId", "req-1")).Sugar()
+
+	s.Infow("order paid", "amount", 3)
+	s.With("userId", "42").Warnf("retry %d", 2)
+	s.Named("payment").Error("card declined")
+	s.Named("payment").With("attempt", 2).Desugar().Info("retrying")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(entries))
+	}
+	for _, e := range entries {
+		fields := e.ContextMap()
+		if fields["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || fields["span_id"] != "00f067aa0ba902b7" {
+			t.Errorf("%q has no correlation fields: %v", e.Message, fields)
+		}
+		if fields["requestId"] != "req-1" {
+			t.Errorf("%q lost the fields of the logger: %v", e.Message, fields)
+		}
+	}
+	if got := entries[0].ContextMap()["amount"]; got != int64(3) {
+		t.Errorf("amount = %v", got)
+	}
+	if e := entries[1]; e.Message != "retry 2" || e.ContextMap()["userId"] != "42" {
+		t.Errorf("got %q %v", e.Message, e.ContextMap())
+	}
+	for _, e := range entries[2:] {
+		if e.LoggerName != "payment" {
+			t.Errorf("%q logged by %q, want payment", e.Message, e.LoggerName)
+		}
+	}
+	if got := entries[3].ContextMap()["attempt"]; got != int64(2) {
+		t.Errorf("attempt = %v", got)
+	}
+}
+
+func TestSweetenFields(t *testing.T) {
+	got := sweetenFields([]interface{}{"userId", "42", zap.Int("amount", 3), 7, "x", "dangling"})
+	want := []zap.Field{
+		zap.Any("userId", "42"),
+		zap.Int("amount", 3),
+		zap.Any("ignored", []interface{}{7, "x"}),
+		zap.Any("ignored", "dangling"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
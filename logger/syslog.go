@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogSeverities maps zap levels to RFC 5424 severities.
+var syslogSeverities = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  1,
+	zapcore.FatalLevel:  0,
+}
+
+// SyslogConfig configures a SyslogWriter.
+type SyslogConfig struct {
+	// Network is udp, tcp, unix or unixgram.
+	Network string
+	Addr    string
+	AppName string
+	// Facility defaults to 1 (user-level messages).
+	Facility int
+	Timeout  time.Duration
+}
+
+// SyslogWriter sends entries as RFC 5424 messages. Stream transports use
+// octet-counting framing (RFC 6587).
+type SyslogWriter struct {
+	cfg      SyslogConfig
+	conn     *reconnectingConn
+	hostname string
+	procID   string
+}
+
+func NewSyslogWriter(cfg SyslogConfig) *SyslogWriter {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.Facility == 0 {
+		cfg.Facility = 1
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "-"
+	}
+	host := hostname
+	if host == "" {
+		host = "-"
+	}
+	return &SyslogWriter{
+		cfg:      cfg,
+		conn:     newReconnectingConn(cfg.Network, cfg.Addr, cfg.Timeout),
+		hostname: host,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+}
+
+func (w *SyslogWriter) WriteEntry(ent zapcore.Entry, p []byte) error {
+	// The encoders terminate entries with a line ending, which is not part
+	// of a syslog message.
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+
+	severity, ok := syslogSeverities[ent.Level]
+	if !ok {
+		severity = 6
+	}
+	msg := make([]byte, 0, len(p)+128)
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(w.cfg.Facility*8+severity), 10)
+	msg = append(msg, ">1 "...)
+	msg = ent.Time.UTC().AppendFormat(msg, "2006-01-02T15:04:05.000000Z07:00")
+	msg = append(msg, ' ')
+	msg = append(msg, w.hostname...)
+	msg = append(msg, ' ')
+	msg = append(msg, w.cfg.AppName...)
+	msg = append(msg, ' ')
+	msg = append(msg, w.procID...)
+	msg = append(msg, " - - "...)
+	msg = append(msg, p...)
+
+	if !isDatagram(w.cfg.Network) {
+		framed := strconv.AppendInt(make([]byte, 0, len(msg)+8), int64(len(msg)), 10)
+		framed = append(framed, ' ')
+		msg = append(framed, msg...)
+	}
+	return w.conn.write(msg)
+}
+
+func (w *SyslogWriter) Sync() error {
+	return nil
+}
+
+func (w *SyslogWriter) Close() error {
+	return w.conn.Close()
+}
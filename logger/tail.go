@@ -0,0 +1,253 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// TailConfig configures tail-based logging: entries below WriteLevel which
+// belong to a request are held back and only written if the request fails.
+type TailConfig struct {
+	// WriteLevel is the lowest level written immediately.
+	WriteLevel zapcore.Level
+	// FlushLevel is the lowest level which marks the request as failed and
+	// writes the entries held back so far.
+	FlushLevel zapcore.Level
+	// Size is the number of entries kept per trace. Older ones are dropped.
+	Size int
+	// MaxTraces bounds the number of traces buffered at once. Entries of
+	// further traces are written immediately.
+	MaxTraces int
+}
+
+func tailConfigFromEnv() (TailConfig, bool) {
+	cfg := TailConfig{
+		WriteLevel: zapcore.WarnLevel,
+		FlushLevel: zapcore.ErrorLevel,
+		Size:       envInt("LOG_TAIL_SIZE", 100),
+		MaxTraces:  envInt("LOG_TAIL_MAX_TRACES", 1000),
+	}
+	if level, ok := levelFromEnv("LOG_TAIL_WRITE_LEVEL"); ok {
+		cfg.WriteLevel = level
+	}
+	if level, ok := levelFromEnv("LOG_TAIL_FLUSH_LEVEL"); ok {
+		cfg.FlushLevel = level
+	}
+	return cfg, envBool("LOG_TAIL", false)
+}
+
+type tailEntry struct {
+	core   zapcore.Core
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+// tailBuffer is the ring buffer of the entries held back for one trace.
+type tailBuffer struct {
+	entries []tailEntry
+	next    int
+	full    bool
+	// failed is set once the request failed; entries are then written
+	// immediately.
+	failed bool
+}
+
+func (b *tailBuffer) add(e tailEntry) bool {
+	dropped := b.full
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	b.full = b.full || b.next == 0
+	return dropped
+}
+
+// drain returns the buffered entries in the order they were logged.
+func (b *tailBuffer) drain() []tailEntry {
+	var out []tailEntry
+	if b.full {
+		out = append(out, b.entries[b.next:]...)
+	}
+	out = append(out, b.entries[:b.next]...)
+	b.next, b.full = 0, false
+	return out
+}
+
+// tailRegistry holds the buffers of the traces whose requests are in flight.
+type tailRegistry struct {
+	cfg     TailConfig
+	mu      sync.Mutex
+	buffers map[trace.TraceID]*tailBuffer
+	dropped uint64
+}
+
+func newTailRegistry(cfg TailConfig) *tailRegistry {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+	return &tailRegistry{cfg: cfg, buffers: map[trace.TraceID]*tailBuffer{}}
+}
+
+// open starts buffering the entries of traceID.
+func (r *tailRegistry) open(traceID trace.TraceID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.buffers[traceID]; ok {
+		return
+	}
+	if r.cfg.MaxTraces > 0 && len(r.buffers) >= r.cfg.MaxTraces {
+		return
+	}
+	r.buffers[traceID] = &tailBuffer{entries: make([]tailEntry, r.cfg.Size)}
+}
+
+// close stops buffering traceID and returns the held back entries if the
+// request failed.
+func (r *tailRegistry) close(traceID trace.TraceID, failed bool) []tailEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.buffers[traceID]
+	if !ok {
+		return nil
+	}
+	delete(r.buffers, traceID)
+	if !failed {
+		return nil
+	}
+	return b.drain()
+}
+
+// hold buffers the entry and reports whether it was held back. Entries at
+// FlushLevel return the held back entries to write before them.
+func (r *tailRegistry) hold(traceID trace.TraceID, e tailEntry) (bool, []tailEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.buffers[traceID]
+	if !ok || b.failed {
+		return false, nil
+	}
+	if e.entry.Level >= r.cfg.FlushLevel {
+		b.failed = true
+		return false, b.drain()
+	}
+	if e.entry.Level >= r.cfg.WriteLevel {
+		return false, nil
+	}
+	if b.add(e) {
+		atomic.AddUint64(&r.dropped, 1)
+	}
+	return true, nil
+}
+
+type tailScopeKey struct{}
+
+// tailScope records the traces logged during one request.
+type tailScope struct {
+	mu       sync.Mutex
+	traceIDs []trace.TraceID
+}
+
+// StartTail marks the beginning of a request for tail-based logging. The
+// entries logged with the returned context are held back until EndTail.
+func StartTail(ctx context.Context) context.Context {
+	if tail == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tailScopeKey{}, &tailScope{})
+}
+
+// EndTail marks the end of a request started with StartTail. The entries held
+// back are written if failed is true and discarded otherwise.
+func EndTail(ctx context.Context, failed bool) {
+	scope, ok := ctx.Value(tailScopeKey{}).(*tailScope)
+	if !ok || tail == nil {
+		return
+	}
+	scope.mu.Lock()
+	traceIDs := scope.traceIDs
+	scope.traceIDs = nil
+	scope.mu.Unlock()
+
+	for _, traceID := range traceIDs {
+		for _, e := range tail.close(traceID, failed) {
+			_ = writeThrough(e.core, e.entry, e.fields)
+		}
+	}
+}
+
+// openTail starts buffering traceID if ctx belongs to a request started with
+// StartTail.
+func openTail(ctx context.Context, traceID trace.TraceID) {
+	if tail == nil {
+		return
+	}
+	scope, ok := ctx.Value(tailScopeKey{}).(*tailScope)
+	if !ok {
+		return
+	}
+	scope.mu.Lock()
+	for _, id := range scope.traceIDs {
+		if id == traceID {
+			scope.mu.Unlock()
+			return
+		}
+	}
+	scope.traceIDs = append(scope.traceIDs, traceID)
+	scope.mu.Unlock()
+	tail.open(traceID)
+}
+
+// TailDropped returns the number of entries dropped because a trace buffer
+// was full.
+func TailDropped() uint64 {
+	if tail == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&tail.dropped)
+}
+
+// TailCore holds back the entries of requests in flight in a per-trace ring
+// buffer, see TailConfig.
+type TailCore struct {
+	zapcore.Core
+	registry    *tailRegistry
+	spanContext trace.SpanContext
+}
+
+func (c *TailCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	if sc, ok := spanContextFromFields(fields); ok {
+		clone.spanContext = sc
+	}
+	return &clone
+}
+
+func (c *TailCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *TailCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	sc, ok := spanContextFromFields(fields)
+	if !ok {
+		sc = c.spanContext
+	}
+	if !sc.IsValid() {
+		return writeThrough(c.Core, ent, fields)
+	}
+
+	e := tailEntry{core: c.Core, entry: ent, fields: append([]zapcore.Field{}, fields...)}
+	held, flush := c.registry.hold(sc.TraceID(), e)
+	for _, f := range flush {
+		_ = writeThrough(f.core, f.entry, f.fields)
+	}
+	if held {
+		return nil
+	}
+	return writeThrough(c.Core, ent, fields)
+}
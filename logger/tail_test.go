@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// useTail enables tail-based logging with cfg, writing to an observer, until
+// the test ends.
+func useTail(t *testing.T, cfg TailConfig) *observer.ObservedLogs {
+	t.Helper()
+	saved := tail
+	tail = newTailRegistry(cfg)
+	logs := useObservedSink(t)
+	t.Cleanup(func() {
+		tail = saved
+		build()
+	})
+	return logs
+}
+
+func defaultTailConfig() TailConfig {
+	return TailConfig{WriteLevel: zapcore.WarnLevel, FlushLevel: zapcore.ErrorLevel, Size: 100}
+}
+
+// requestContext returns the context of a request started with StartTail.
+func requestContext(t *testing.T) context.Context {
+	return StartTail(trace.ContextWithSpanContext(context.Background(), testSpanContext(t)))
+}
+
+func messages(logs *observer.ObservedLogs) []string {
+	var out []string
+	for _, e := range logs.All() {
+		out = append(out, e.Message)
+	}
+	return out
+}
+
+func TestTailDiscardsSucceededRequests(t *testing.T) {
+	logs := useTail(t, defaultTailConfig())
+	ctx := requestContext(t)
+	Ctx(ctx).Debug("cache miss")
+	Ctx(ctx).Info("order loaded")
+	Ctx(ctx).Warn("slow query")
+	// A 2xx response.
+	EndTail(ctx, false)
+
+	if got, want := messages(logs), []string{"slow query"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
+
+func TestTailWritesFailedRequests(t *testing.T) {
+	logs := useTail(t, defaultTailConfig())
+	ctx := requestContext(t)
+	Ctx(ctx).Debug("cache miss")
+	Ctx(ctx).Info("order loaded")
+	if n := logs.Len(); n != 0 {
+		t.Fatalf("wrote %d entries before the request ended", n)
+	}
+	// A 5xx response.
+	EndTail(ctx, true)
+
+	if got, want := messages(logs), []string{"cache miss", "order loaded"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
+
+func TestTailFlushesOnError(t *testing.T) {
+	logs := useTail(t, defaultTailConfig())
+	ctx := requestContext(t)
+	Ctx(ctx).Info("order loaded")
+	Ctx(ctx).Error("payment failed")
+	// Entries after the error are no longer held back.
+	Ctx(ctx).Info("order cancelled")
+	want := []string{"order loaded", "payment failed", "order cancelled"}
+	if got := messages(logs); !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+
+	EndTail(ctx, false)
+	if got := messages(logs); !reflect.DeepEqual(got, want) {
+		t.Errorf("messages after the request = %q, want %q", got, want)
+	}
+}
+
+func TestTailEvictsOldestEntries(t *testing.T) {
+	cfg := defaultTailConfig()
+	cfg.Size = 3
+	logs := useTail(t, cfg)
+	ctx := requestContext(t)
+	for _, msg := range []string{"one", "two", "three", "four", "five"} {
+		Ctx(ctx).Info(msg, zap.String("step", msg))
+	}
+	EndTail(ctx, true)
+
+	if got, want := messages(logs), []string{"three", "four", "five"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+	if got := TailDropped(); got != 2 {
+		t.Errorf("dropped %d entries, want 2", got)
+	}
+}
+
+func TestTailWritesEntriesOutsideRequests(t *testing.T) {
+	cfg := defaultTailConfig()
+	cfg.MaxTraces = 1
+	logs := useTail(t, cfg)
+	Ctx(context.Background()).Info("without a trace")
+	// Not started with StartTail.
+	Ctx(trace.ContextWithSpanContext(context.Background(), testSpanContext(t))).Info("background job")
+
+	held := requestContext(t)
+	Ctx(held).Info("held back")
+	other := StartTail(trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1},
+	})))
+	Ctx(other).Info("beyond MaxTraces")
+
+	if got, want := messages(logs), []string{"without a trace", "background job", "beyond MaxTraces"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 
 	log "github.com/vaish1707/golang-logging-instrumentation/logger"
 )
@@ -46,51 +47,81 @@ func createOrder() http.HandlerFunc {
 			return
 		}
 
-		metadata := utils.GetExtraFields(r, request.UserID, "order-service", "createOrder")
+		log.AddFields(r.Context(), zap.String("userId", request.UserID))
 
-		log.Ctx(r.Context()).Info("Order controller called", metadata...)
+		log.FromContext(r.Context()).Info("Order controller called")
 
-		// get user details from user service
+		// get user details from user service, which logs the userId carried in baggage
+		ctx := log.ContextWithBaggage(r.Context(), "userId", request.UserID)
 		url := fmt.Sprintf("http://%s/users/%s", userUrl, request.UserID)
-		userResponse, err := utils.SendRequest(r.Context(), http.MethodGet, url, nil)
+		userResponse, err := utils.SendRequest(ctx, http.MethodGet, url, nil)
 		if err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to get user")
 			utils.WriteResponse(w, http.StatusInternalServerError, err)
 			return
 		}
 
 		b, err := ioutil.ReadAll(userResponse.Body)
 		if err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to read user response")
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
 			return
 		}
 		defer userResponse.Body.Close()
 
 		if userResponse.StatusCode != http.StatusOK {
-			log.Ctx(r.Context()).Error(fmt.Errorf("payment failed. got response: %s", b).Error(), metadata...)
+			log.FromContext(r.Context()).Error(fmt.Errorf("payment failed. got response: %s", b).Error())
 			utils.WriteErrorResponse(w, userResponse.StatusCode, fmt.Errorf("payment failed. got response: %s", b))
 			return
 		}
 
 		var userDat userData
 		if err := json.Unmarshal(b, &userDat); err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to decode user response")
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
 			return
 		}
-		ctx := r.Context()
 		span := trace.SpanFromContext(ctx)
 
 		// basic check for the user balance
 		if userDat.Amount < request.Price {
 			span.RecordError(errors.New("insufficient balance"))
 			span.SetStatus(codes.Error, "failed due to insufficient balance")
-			log.Ctx(r.Context()).Warn(fmt.Errorf("insufficient balance. add %d more amount to account", request.Price-userDat.Amount).Error(), metadata...)
+			log.FromContext(r.Context()).Warn(fmt.Errorf("insufficient balance. add %d more amount to account", request.Price-userDat.Amount).Error())
 			utils.WriteErrorResponse(w, http.StatusUnprocessableEntity, fmt.Errorf("insufficient balance. add %d more amount to account", request.Price-userDat.Amount))
 			return
 		}
 
+		usercollection := mongodbClient.MongoClient.Database("otel").Collection("users")
+
+		filter := bson.M{"userid": userDat.UserID}
+		singleUserData := &userData{}
+		singleUser := usercollection.FindOne(r.Context(), filter)
+
+		if err := singleUser.Decode(singleUserData); err != nil {
+			log.FromContext(r.Context()).Err(err, "Failed to find user")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		event := log.AuditEvent{
+			Action: "debit",
+			Actor:  serviceName,
+			// Taken from the request body and not authenticated.
+			RequestedBy: request.UserID,
+			UserID:      userDat.UserID,
+			Before:      singleUserData.Amount,
+			After:       singleUserData.Amount - request.Price,
+			RequestID:   r.Header.Get("requestId"),
+		}
+		// Neither the order nor the balance change until the debit is on
+		// record.
+		if err := log.Audit(r.Context(), event); err != nil {
+			log.FromContext(r.Context()).Err(err, "Failed to write audit record")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+
 		// insert the order into order table
 		ordercollection := mongodbClient.MongoClient.Database("otel").Collection("orders")
 
@@ -105,36 +136,36 @@ func createOrder() http.HandlerFunc {
 
 		_, mongoErr := ordercollection.InsertOne(r.Context(), orderData)
 		if mongoErr != nil {
-			log.Ctx(r.Context()).Error(mongoErr.Error(), metadata...)
+			log.FromContext(r.Context()).Err(mongoErr, "Failed to insert order")
+			revertAudit(r, event)
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, mongoErr)
 			return
 		}
 
 		// update the pending amount in user table
-		usercollection := mongodbClient.MongoClient.Database("otel").Collection("users")
-
-		filter := bson.M{"userid": userDat.UserID}
-		singleUserData := &userData{}
-		singleUser := usercollection.FindOne(r.Context(), filter)
-
-		if err := singleUser.Decode(singleUserData); err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
-			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
-			return
-		}
-
-		singleUserData.Amount = singleUserData.Amount - request.Price
+		singleUserData.Amount = event.After
 
 		_, updateErr := usercollection.ReplaceOne(r.Context(), filter, singleUserData)
 		if updateErr != nil {
-			log.Ctx(r.Context()).Error(updateErr.Error(), metadata...)
-			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
+			log.FromContext(r.Context()).Err(updateErr, "Failed to update user balance")
+			if _, err := ordercollection.DeleteOne(r.Context(), bson.M{"id": orderData.ID}); err != nil {
+				log.FromContext(r.Context()).Err(err, "Failed to delete unpaid order", zap.String("orderId", orderData.ID))
+			}
+			revertAudit(r, event)
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, updateErr)
 			return
 		}
 
-		log.Ctx(r.Context()).Info("Successfully completed order request", metadata...)
+		log.FromContext(r.Context()).Info("Successfully completed order request")
 		// send response
 		response := request
 		utils.WriteResponse(w, http.StatusCreated, response)
 	}
 }
+
+// revertAudit records that the debit of event was not applied.
+func revertAudit(r *http.Request, event log.AuditEvent) {
+	if err := log.RevertAudit(r.Context(), event); err != nil {
+		log.FromContext(r.Context()).Err(err, "Failed to write audit record")
+	}
+}
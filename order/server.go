@@ -14,6 +14,7 @@ import (
 	"github.com/rs/cors"
 	"github.com/vaish1707/golang-logging-instrumentation/config"
 	"github.com/vaish1707/golang-logging-instrumentation/datastore"
+	"github.com/vaish1707/golang-logging-instrumentation/logger"
 	"github.com/vaish1707/golang-logging-instrumentation/utils"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
@@ -33,8 +34,16 @@ var (
 func setupServer() {
 	router := mux.NewRouter()
 	router.HandleFunc("/orders", otelhttp.NewHandler(createOrder(), "CreateOrder").ServeHTTP).Methods(http.MethodPost)
-	router.Use(utils.LoggingMW)
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(logger.AdminAuth)
+	admin.Handle("/loglevel", logger.LevelHandler()).Methods(http.MethodGet, http.MethodPut)
+	admin.Handle("/logsampling", logger.SamplingHandler()).Methods(http.MethodGet)
+	admin.Handle("/logqueue", logger.AsyncHandler()).Methods(http.MethodGet)
+	router.Handle("/metrics", config.MetricsHandler()).Methods(http.MethodGet)
 	router.Use(utils.LogRequestID)
+	router.Use(utils.RequestLogger)
+	router.Use(utils.LoggingMW)
+	router.Use(utils.MetricsMW)
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{http.MethodGet, http.MethodPut, http.MethodPost},
@@ -64,6 +73,7 @@ func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file", err)
 	}
+	logger.SetupLog(serviceName)
 	orderUrl = os.Getenv("ORDER_URL")
 	userUrl = os.Getenv("USER_URL")
 
@@ -77,6 +87,22 @@ func main() {
 			log.Printf("Error shutting down tracer provider: %v", err)
 		}
 	}()
+
+	// setup metrics
+	mp, err := config.InitMetrics(serviceName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down meter provider: %v", err)
+		}
+	}()
+	defer func() {
+		if err := logger.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down logger: %v", err)
+		}
+	}()
 	tracer = otel.Tracer(serviceName)
 
 	sigint := make(chan os.Signal, 1)
@@ -9,6 +9,7 @@ import (
 	"github.com/gorilla/mux"
 	log "github.com/vaish1707/golang-logging-instrumentation/logger"
 	"github.com/vaish1707/golang-logging-instrumentation/utils"
+	"go.uber.org/zap"
 )
 
 type paymentData struct {
@@ -19,47 +20,48 @@ func transferAmount() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := mux.Vars(r)["userID"]
 
-		metadata := utils.GetExtraFields(r, userID, "payment-service", "transferAmount")
+		log.AddFields(r.Context(), zap.String("userId", userID))
 
-		log.Ctx(r.Context()).Info("Payment controller called", metadata...)
+		log.FromContext(r.Context()).Info("Payment controller called")
 
 		var data paymentData
 		if err := utils.ReadBody(w, r, &data); err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to read request body")
 			return
 		}
 
 		payload, err := json.Marshal(data)
 		if err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to encode payment")
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		// send the request to user service
+		// send the request to user service, which logs the userId carried in baggage
+		ctx := log.ContextWithBaggage(r.Context(), "userId", userID)
 		url := fmt.Sprintf("http://%s/users/%s", userUrl, userID)
-		resp, err := utils.SendRequest(r.Context(), http.MethodPut, url, payload)
+		resp, err := utils.SendRequest(ctx, http.MethodPut, url, payload)
 		if err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to update user balance")
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
 			return
 		}
 
 		b, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to read user response")
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			log.Ctx(r.Context()).Error(fmt.Errorf("payment failed. got response: %s", b).Error(), metadata...)
+			log.FromContext(r.Context()).Error(fmt.Errorf("payment failed. got response: %s", b).Error())
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, fmt.Errorf("payment failed. got response: %s", b))
 			return
 		}
 
-		log.Ctx(r.Context()).Info("Successfully completed payment request", metadata...)
+		log.FromContext(r.Context()).Info("Successfully completed payment request")
 
 		utils.WriteResponse(w, http.StatusOK, data)
 	}
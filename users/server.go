@@ -14,6 +14,7 @@ import (
 	"github.com/rs/cors"
 	"github.com/vaish1707/golang-logging-instrumentation/config"
 	"github.com/vaish1707/golang-logging-instrumentation/datastore"
+	"github.com/vaish1707/golang-logging-instrumentation/logger"
 	"github.com/vaish1707/golang-logging-instrumentation/utils"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
@@ -34,8 +35,16 @@ func setupServer() {
 	router.HandleFunc("/users", otelhttp.NewHandler(createUser(), "createuser").ServeHTTP).Methods(http.MethodPost, http.MethodOptions)
 	router.HandleFunc("/users/{userID}", otelhttp.NewHandler(getUser(), "getuser").ServeHTTP).Methods(http.MethodGet, http.MethodOptions)
 	router.HandleFunc("/users/{userID}", otelhttp.NewHandler(updateUser(), "updateuser").ServeHTTP).Methods(http.MethodPut, http.MethodOptions)
-	router.Use(utils.LoggingMW)
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(logger.AdminAuth)
+	admin.Handle("/loglevel", logger.LevelHandler()).Methods(http.MethodGet, http.MethodPut)
+	admin.Handle("/logsampling", logger.SamplingHandler()).Methods(http.MethodGet)
+	admin.Handle("/logqueue", logger.AsyncHandler()).Methods(http.MethodGet)
+	router.Handle("/metrics", config.MetricsHandler()).Methods(http.MethodGet)
 	router.Use(utils.LogRequestID)
+	router.Use(utils.RequestLogger)
+	router.Use(utils.LoggingMW)
+	router.Use(utils.MetricsMW)
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{http.MethodGet, http.MethodPut, http.MethodPost},
@@ -65,6 +74,7 @@ func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file", err)
 	}
+	logger.SetupLog(serviceName)
 	userUrl = os.Getenv("USER_URL")
 
 	// setup tracer
@@ -78,6 +88,22 @@ func main() {
 			log.Printf("Error shutting down tracer provider: %v", err)
 		}
 	}()
+
+	// setup metrics
+	mp, err := config.InitMetrics(serviceName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := mp.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down meter provider: %v", err)
+		}
+	}()
+	defer func() {
+		if err := logger.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down logger: %v", err)
+		}
+	}()
 	tracer = otel.Tracer(serviceName)
 
 	sigint := make(chan os.Signal, 1)
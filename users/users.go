@@ -8,7 +8,7 @@ import (
 	log "github.com/vaish1707/golang-logging-instrumentation/logger"
 	"github.com/vaish1707/golang-logging-instrumentation/utils"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap"
 )
 
 type user struct {
@@ -26,22 +26,22 @@ func createUser() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var u user
 		if err := utils.ReadBody(w, r, &u); err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), []zapcore.Field{}...)
+			log.FromContext(r.Context()).Err(err, "Failed to read request body")
 			return
 		}
-		metadata := utils.GetExtraFields(r, u.UserID, "user-service", "createUser")
+		log.AddFields(r.Context(), zap.String("userId", u.UserID))
 
-		log.Ctx(r.Context()).Info("Create user controller called", metadata...)
+		log.FromContext(r.Context()).Info("Create user controller called")
 
 		usercollection := mongodbClient.MongoClient.Database("otel").Collection("users")
 		_, mongoErr := usercollection.InsertOne(r.Context(), u)
 		if mongoErr != nil {
-			log.Ctx(r.Context()).Error(mongoErr.Error(), metadata...)
+			log.FromContext(r.Context()).Err(mongoErr, "Failed to insert user")
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, mongoErr)
 			return
 		}
 
-		log.Ctx(r.Context()).Info("Successfully completed create user request", metadata...)
+		log.FromContext(r.Context()).Info("Successfully completed create user request")
 
 		utils.WriteResponse(w, http.StatusCreated, u)
 	}
@@ -51,9 +51,9 @@ func getUser() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := mux.Vars(r)["userID"]
 
-		metadata := utils.GetExtraFields(r, userID, "user-service", "getUser")
+		log.AddFields(r.Context(), zap.String("userId", userID))
 
-		log.Ctx(r.Context()).Info("Get user controller called", metadata...)
+		log.FromContext(r.Context()).Info("Get user controller called")
 
 		usercollection := mongodbClient.MongoClient.Database("otel").Collection("users")
 
@@ -63,12 +63,12 @@ func getUser() http.HandlerFunc {
 
 		res := usercollection.FindOne(r.Context(), filter)
 		if err := res.Decode(data); err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to find user")
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, fmt.Errorf("get user error: %w", err))
 			return
 		}
 
-		log.Ctx(r.Context()).Info("Successfully completed get user request", metadata...)
+		log.FromContext(r.Context()).Info("Successfully completed get user request")
 
 		utils.WriteResponse(w, http.StatusOK, data)
 	}
@@ -78,13 +78,13 @@ func updateUser() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := mux.Vars(r)["userID"]
 
-		metadata := utils.GetExtraFields(r, userID, "user-service", "updateUser")
+		log.AddFields(r.Context(), zap.String("userId", userID))
 
-		log.Ctx(r.Context()).Info("Update user controller called", metadata...)
+		log.FromContext(r.Context()).Info("Update user controller called")
 
 		var data paymentData
 		if err := utils.ReadBody(w, r, &data); err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to read request body")
 			return
 		}
 		usercollection := mongodbClient.MongoClient.Database("otel").Collection("users")
@@ -96,20 +96,39 @@ func updateUser() http.HandlerFunc {
 		singleUser := usercollection.FindOne(r.Context(), filter)
 
 		if err := singleUser.Decode(userDat); err != nil {
-			log.Ctx(r.Context()).Error(err.Error(), metadata...)
+			log.FromContext(r.Context()).Err(err, "Failed to find user")
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
 			return
 		}
-		userDat.Amount = userDat.Amount + data.Amount
+		event := log.AuditEvent{
+			Action: "top-up",
+			Actor:  serviceName,
+			// Set by the calling service and not authenticated.
+			RequestedBy: log.BaggageValue(r.Context(), "userId"),
+			UserID:      userID,
+			Before:      userDat.Amount,
+			After:       userDat.Amount + data.Amount,
+			RequestID:   r.Header.Get("requestId"),
+		}
+		// The balance only changes once the change is on record.
+		if err := log.Audit(r.Context(), event); err != nil {
+			log.FromContext(r.Context()).Err(err, "Failed to write audit record")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		userDat.Amount = event.After
 
 		_, updateErr := usercollection.ReplaceOne(r.Context(), filter, userDat)
 		if updateErr != nil {
-			log.Ctx(r.Context()).Error(updateErr.Error(), metadata...)
+			log.FromContext(r.Context()).Err(updateErr, "Failed to update user")
+			if err := log.RevertAudit(r.Context(), event); err != nil {
+				log.FromContext(r.Context()).Err(err, "Failed to write audit record")
+			}
 			utils.WriteErrorResponse(w, http.StatusInternalServerError, updateErr)
 			return
 		}
 
-		log.Ctx(r.Context()).Info("Successfully completed update user request", metadata...)
+		log.FromContext(r.Context()).Info("Successfully completed update user request")
 
 		w.WriteHeader(http.StatusOK)
 	}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+)
+
+const meterName = "github.com/vaish1707/golang-logging-instrumentation/utils"
+
+// redMetrics are the rate, errors and duration of HTTP requests.
+type redMetrics struct {
+	requests syncint64.Counter
+	errors   syncint64.Counter
+	duration syncfloat64.Histogram
+}
+
+var (
+	metricsOnce   sync.Once
+	serverMetrics redMetrics
+	clientMetrics redMetrics
+)
+
+// httpMetrics creates the instruments on first use. The global meter
+// provider forwards them to the one set by config.InitMetrics.
+func httpMetrics() (server, client *redMetrics) {
+	metricsOnce.Do(func() {
+		meter := global.Meter(meterName)
+		serverMetrics = newREDMetrics(meter.SyncInt64(), meter.SyncFloat64(), "http.server")
+		clientMetrics = newREDMetrics(meter.SyncInt64(), meter.SyncFloat64(), "http.client")
+	})
+	return &serverMetrics, &clientMetrics
+}
+
+func newREDMetrics(ints syncint64.InstrumentProvider, floats syncfloat64.InstrumentProvider, prefix string) redMetrics {
+	var m redMetrics
+	var err error
+	if m.requests, err = ints.Counter(prefix+".requests",
+		instrument.WithDescription("Number of HTTP requests")); err != nil {
+		otel.Handle(err)
+	}
+	if m.errors, err = ints.Counter(prefix+".errors",
+		instrument.WithDescription("Number of HTTP requests which failed or returned a 5xx status")); err != nil {
+		otel.Handle(err)
+	}
+	if m.duration, err = floats.Histogram(prefix+".request.duration",
+		instrument.WithDescription("Duration of HTTP requests"),
+		instrument.WithUnit(unit.Milliseconds)); err != nil {
+		otel.Handle(err)
+	}
+	return m
+}
+
+func (m *redMetrics) record(ctx context.Context, start time.Time, failed bool, attrs ...attribute.KeyValue) {
+	if m.requests == nil || m.errors == nil || m.duration == nil {
+		return
+	}
+	m.requests.Add(ctx, 1, attrs...)
+	if failed {
+		m.errors.Add(ctx, 1, attrs...)
+	}
+	m.duration.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs...)
+}
+
+// MetricsMW records the count, errors and duration of requests per method,
+// route and status code.
+func MetricsMW(next http.Handler) http.Handler {
+	server, _ := httpMetrics()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := newResponseWriter(w)
+		next.ServeHTTP(rw, r)
+		server.record(r.Context(), start, rw.statusCode >= http.StatusInternalServerError,
+			attribute.String("http.method", r.Method),
+			attribute.String("http.route", routeOf(r)),
+			attribute.Int("http.status_code", rw.statusCode),
+		)
+	})
+}
+
+// recordClientRequest records an outgoing request per method, host and
+// status code. The status code is 0 if no response was received.
+func recordClientRequest(ctx context.Context, start time.Time, request *http.Request, response *http.Response, err error) {
+	_, client := httpMetrics()
+	statusCode := 0
+	if response != nil {
+		statusCode = response.StatusCode
+	}
+	client.record(ctx, start, err != nil || statusCode >= http.StatusInternalServerError,
+		attribute.String("http.method", request.Method),
+		attribute.String("net.peer.name", request.URL.Host),
+		attribute.Int("http.status_code", statusCode),
+	)
+}
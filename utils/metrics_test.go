@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// useMeterReader registers a meter provider with a manual reader globally
+// and creates the instruments with it until the test ends.
+func useMeterReader(t *testing.T) sdkmetric.Reader {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	saved := global.MeterProvider()
+	global.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	metricsOnce = sync.Once{}
+	t.Cleanup(func() {
+		global.SetMeterProvider(saved)
+		metricsOnce = sync.Once{}
+	})
+	return reader
+}
+
+// collect returns the counts of the counters and histograms read by reader
+// per instrument and encoded attribute set.
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]map[string]int64 {
+	t.Helper()
+	rm, err := reader.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			points := map[string]int64{}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					points[dp.Attributes.Encoded(attribute.DefaultEncoder())] = dp.Value
+				}
+			case metricdata.Histogram:
+				for _, dp := range data.DataPoints {
+					points[dp.Attributes.Encoded(attribute.DefaultEncoder())] = int64(dp.Count)
+				}
+			}
+			counts[m.Name] = points
+		}
+	}
+	return counts
+}
+
+func TestREDMetrics(t *testing.T) {
+	reader := useMeterReader(t)
+
+	router := mux.NewRouter()
+	router.Use(MetricsMW)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "bad" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	for _, path := range []string{"/users/1", "/users/2", "/users/bad"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	host := upstream.Listener.Addr().String()
+	response, err := SendRequest(context.Background(), http.MethodPost, upstream.URL+"/payments", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	upstream.Close()
+	if _, err := SendRequest(context.Background(), http.MethodPost, upstream.URL+"/payments", nil); err == nil {
+		t.Fatal("sent a request to a closed server")
+	}
+
+	ok := "http.method=GET,http.route=/users/{id},http.status_code=200"
+	failed := "http.method=GET,http.route=/users/{id},http.status_code=500"
+	unavailable := "http.method=POST,http.status_code=503,net.peer.name=" + host
+	unreachable := "http.method=POST,http.status_code=0,net.peer.name=" + host
+	want := map[string]map[string]int64{
+		"http.server.requests":         {ok: 2, failed: 1},
+		"http.server.errors":           {failed: 1},
+		"http.server.request.duration": {ok: 2, failed: 1},
+		"http.client.requests":         {unavailable: 1, unreachable: 1},
+		"http.client.errors":           {unavailable: 1, unreachable: 1},
+		"http.client.request.duration": {unavailable: 1, unreachable: 1},
+	}
+	got := collect(t, reader)
+	for name, points := range want {
+		if !reflect.DeepEqual(got[name], points) {
+			t.Errorf("%s = %v, want %v", name, got[name], points)
+		}
+	}
+}
@@ -8,13 +8,11 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
-	"runtime"
-	"strings"
 	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/vaish1707/golang-logging-instrumentation/logger"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
@@ -24,50 +22,6 @@ type errResponse struct {
 	Message string `json:"message"`
 }
 
-type metadata struct {
-	ReqId       string
-	UserId      string
-	UserAgent   string
-	ReqMethod   string
-	ReqPath     string
-	Host        string
-	ServiceName string
-	MethodName  string
-}
-
-func GetExtraFields(r *http.Request, userId string, serviceName string, methodName string) []zap.Field {
-	hostname, _ := os.Hostname()
-	userAgent := r.UserAgent()
-	fields := buildFields(metadata{ReqId: r.Header.Get("requestId"), UserId: userId, UserAgent: userAgent, ReqMethod: r.Method, ReqPath: r.URL.Path, Host: hostname, ServiceName: serviceName, MethodName: methodName})
-	return fields
-}
-
-func shortPath(path string) string {
-	dirs := strings.Split(path, "/")
-	if len(dirs) > 1 {
-		return strings.Join(dirs[len(dirs)-2:], "/")
-	}
-	return path
-}
-
-func buildLine() zap.Field {
-	_, fn, line, _ := runtime.Caller(3) // reach back into callstack for log.* call
-	return zap.String("line", fmt.Sprintf("%s:%d", shortPath(fn), line))
-}
-
-func buildFields(m metadata) []zap.Field {
-	requestId := zap.String("requestId", m.ReqId)
-	userId := zap.String("userId", m.UserId)
-	userAgent := zap.String("userAgent", m.UserAgent)
-	reqMethod := zap.String("requestMethod", m.ReqMethod)
-	reqPath := zap.String("requestPath", m.ReqPath)
-	host := zap.String("hostname", m.Host)
-	serviceName := zap.String("serviceName", m.ServiceName)
-	methodName := zap.String("methodName", m.MethodName)
-	fields := []zap.Field{buildLine(), requestId, userId, userAgent, reqMethod, reqPath, host, serviceName, methodName}
-	return fields
-}
-
 func ReadBody(w http.ResponseWriter, r *http.Request, obj interface{}) error {
 	// read body
 	body, err := ioutil.ReadAll(r.Body)
@@ -115,7 +69,10 @@ func SendRequest(ctx context.Context, method string, url string, data []byte) (*
 		Timeout:   10 * time.Second,
 	}
 
-	return client.Do(request)
+	start := time.Now()
+	response, err := client.Do(request)
+	recordClientRequest(ctx, start, request, response, err)
+	return response, err
 }
 
 type responseWriter struct {
@@ -125,6 +82,7 @@ type responseWriter struct {
 
 func (rw *responseWriter) WriteHeader(statusCode int) {
 	rw.statusCode = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
 func newResponseWriter(w http.ResponseWriter) *responseWriter {
@@ -144,13 +102,16 @@ func LoggingMW(next http.Handler) http.Handler {
 		var buf bytes.Buffer
 		tee := io.TeeReader(r.Body, &buf)
 		r.Body = ioutil.NopCloser(tee)
+		// Debug and info logs of the request are only written if it fails.
+		r = r.WithContext(logger.StartTail(r.Context()))
 		next.ServeHTTP(rw, r)
+		logger.EndTail(r.Context(), rw.statusCode >= http.StatusInternalServerError)
 		duration := time.Since(start)
 		statusCode := zap.Int("statusCode", rw.statusCode)
 		reqbody := zap.String("requestBody", buf.String())
 		timeTaken := zap.Int64("duration", duration.Milliseconds())
 		fields := []zap.Field{statusCode, reqbody, timeTaken}
-		logger.Ctx(r.Context()).Info("Request completed",
+		logger.FromContext(r.Context()).Info("Request completed",
 			fields...)
 	})
 }
@@ -162,3 +123,30 @@ func LogRequestID(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequestLogger stores a request-scoped logger in the request context, which
+// handlers retrieve with logger.FromContext. It must run after LogRequestID.
+// The service and host are logged as the resource attributes set by
+// config.Init.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := logger.Ctx(r.Context()).With(
+			zap.String("requestId", r.Header.Get("requestId")),
+			zap.String("requestMethod", r.Method),
+			zap.String("requestPath", r.URL.Path),
+			zap.String("route", routeOf(r)),
+			zap.String("userAgent", r.UserAgent()),
+		)
+		next.ServeHTTP(w, r.WithContext(logger.NewContext(r.Context(), l)))
+	})
+}
+
+// routeOf returns the path template of the route matching r, or else its path.
+func routeOf(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}